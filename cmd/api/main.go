@@ -16,19 +16,44 @@ func main() {
 	ctx := context.Background()
 
 	cfg := config.Load()
-	pool := db.NewPool(cfg.DatabaseURL)
-	defer pool.Close()
-
-	repo := rag.NewPgRepository(pool)
+	repo, closeStore, err := db.NewStore(cfg)
+	if err != nil {
+		log.Fatalf("failed to open storage: %v", err)
+	}
+	defer closeStore()
 
-	geminiClient, err := llm.NewGeminiClient(ctx)
+	backend, err := llm.NewBackend(ctx, cfg)
 	if err != nil {
-		log.Fatalf("failed to init Gemini client: %v", err)
+		log.Fatalf("failed to init LLM backend: %v", err)
+	}
+	log.Printf("LLM backend: %s", backend.Name)
+
+	opts := []rag.Option{rag.WithMinSimilarity(cfg.MinSimilarity), rag.WithProviders(repo), rag.WithEndpoints(repo), rag.WithErrorCodes(repo)}
+	if cfg.Conversations {
+		opts = append(opts, rag.WithConversations(repo))
+	}
+	if cfg.GroundingCheck {
+		opts = append(opts, rag.WithGroundingCheck(cfg.GroundingRetry))
+	}
+	switch mode := rag.IntentMode(cfg.IntentRouting); mode {
+	case rag.IntentOff, rag.IntentBoost, rag.IntentFilter:
+		opts = append(opts, rag.WithIntentRouting(mode))
+	default:
+		log.Fatalf("invalid INTENT_ROUTING %q (use off, boost ou filter)", cfg.IntentRouting)
+	}
+	switch cfg.Reranker {
+	case "", "none":
+	case "lexical":
+		opts = append(opts, rag.WithReranker(rag.NewLexicalReranker()))
+	case "llm":
+		opts = append(opts, rag.WithReranker(llm.NewLLMReranker(backend.Completer)))
+	default:
+		log.Fatalf("invalid RERANKER %q (use none, lexical ou llm)", cfg.Reranker)
 	}
 
-	ragService := rag.NewService(repo, geminiClient, geminiClient)
+	ragService := rag.NewService(repo, backend.Embeddings, backend.LLM, opts...)
 
-	h := apphttp.NewHandler(ragService)
+	h := apphttp.NewHandler(ragService, apphttp.WithAskTimeout(cfg.AskTimeout))
 	router := apphttp.NewRouter(h)
 
 	handler := corsMiddleware(router)
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+	"golang.org/x/net/html"
+)
+
+// chunkOptions define o tamanho dos chunks em tokens aproximados (ver approxTokens)
+// e quantos tokens do fim de um chunk são repetidos no começo do próximo.
+type chunkOptions struct {
+	maxTokens     int
+	overlapTokens int
+}
+
+// charsPerToken é a média usada para estimar tokens sem tokenizer:
+// ~4 caracteres por token vale razoavelmente para pt/en nos modelos do Gemini.
+const charsPerToken = 4
+
+func approxTokens(s string) int {
+	n := utf8.RuneCountInString(s)
+	return (n + charsPerToken - 1) / charsPerToken
+}
+
+// section é um trecho da doc delimitado por headings.
+// breadcrumb é o caminho de títulos até ele (h1 > h2 > ...), blocks são
+// unidades que não devem ser quebradas no meio (parágrafo, bloco de código, tabela).
+type section struct {
+	breadcrumb []string
+	blocks     []string
+}
+
+// piece é um chunk pronto para embedding/armazenamento.
+// SectionType/Tags vêm preenchidos quando a origem já é estruturada (ex: OpenAPI);
+// senão são detectados pelo conteúdo.
+type piece struct {
+	Title       string
+	Content     string
+	SectionType rag.SectionType
+	Tags        []string
+}
+
+// packSections transforma seções em chunks: seção que cabe em maxTokens vira um chunk só;
+// seção maior é agrupada por blocos e só blocos maiores que o limite caem no splitIntoChunks
+// (tabelas no splitTable). Partes consecutivas da mesma seção repetem overlapTokens do fim
+// da anterior, menos a continuação de tabela, que já começa repetindo o cabeçalho.
+// O breadcrumb de headings vai para o título do chunk.
+func packSections(docTitle string, sections []section, opts chunkOptions) []piece {
+	var pieces []piece
+
+	for _, sec := range sections {
+		title := docTitle
+		if len(sec.breadcrumb) > 0 {
+			title = docTitle + " > " + strings.Join(sec.breadcrumb, " > ")
+		}
+
+		var header string
+		if len(sec.breadcrumb) > 0 {
+			header = sec.breadcrumb[len(sec.breadcrumb)-1] + "\n\n"
+		}
+
+		// reserva espaço para o header e para o overlap que entra na frente de cada parte
+		budget := max(opts.maxTokens-approxTokens(header)-opts.overlapTokens, 1)
+
+		var parts []string
+		tableCont := make(map[int]bool) // partes que continuam uma tabela quebrada
+		var buf strings.Builder
+		flush := func() {
+			if strings.TrimSpace(buf.String()) != "" {
+				parts = append(parts, strings.TrimSpace(buf.String()))
+			}
+			buf.Reset()
+		}
+
+		for _, b := range sec.blocks {
+			b = strings.TrimSpace(b)
+			if b == "" {
+				continue
+			}
+
+			if approxTokens(b) > budget {
+				flush()
+				if strings.HasPrefix(b, "|") {
+					for i, p := range splitTable(b, budget) {
+						tableCont[len(parts)] = i > 0
+						parts = append(parts, p)
+					}
+					continue
+				}
+				parts = append(parts, splitIntoChunks(b, budget)...)
+				continue
+			}
+
+			if buf.Len() > 0 && approxTokens(buf.String())+approxTokens(b) > budget {
+				flush()
+			}
+			if buf.Len() > 0 {
+				buf.WriteString("\n\n")
+			}
+			buf.WriteString(b)
+		}
+		flush()
+
+		for i, p := range parts {
+			t := title
+			if len(parts) > 1 {
+				t = fmt.Sprintf("%s (parte %d)", title, i+1)
+			}
+			if i > 0 && opts.overlapTokens > 0 && !tableCont[i] {
+				p = tailTokens(parts[i-1], opts.overlapTokens) + "\n" + p
+			}
+			pieces = append(pieces, piece{Title: t, Content: header + p})
+		}
+	}
+
+	return pieces
+}
+
+// splitTable quebra uma tabela Markdown grande por linhas e repete o cabeçalho (e a linha
+// separadora, se houver) no começo de cada parte: sem ele, a continuação vira uma tabela
+// sem colunas e o ExtractErrorCodes, por exemplo, não sabe o que é código e o que é mensagem.
+func splitTable(table string, maxTokens int) []string {
+	lines := strings.Split(table, "\n")
+	n := 1
+	if len(lines) > 1 && tableSeparatorRe.MatchString(strings.TrimSpace(lines[1])) {
+		n = 2
+	}
+	header := strings.Join(lines[:n], "\n")
+	if len(lines) == n {
+		return splitIntoChunks(table, maxTokens)
+	}
+
+	rows := splitIntoChunks(strings.Join(lines[n:], "\n"), max(maxTokens-approxTokens(header), 1))
+	parts := make([]string, 0, len(rows))
+	for _, r := range rows {
+		parts = append(parts, header+"\n"+r)
+	}
+	return parts
+}
+
+var tableSeparatorRe = regexp.MustCompile(`^\|?(\s*:?-{3,}:?\s*\|)+\s*:?-*:?\s*$`)
+
+// tailTokens devolve ~n tokens do fim de s, começando numa fronteira de palavra.
+func tailTokens(s string, n int) string {
+	runes := []rune(s)
+	limit := n * charsPerToken
+	if len(runes) <= limit {
+		return s
+	}
+	tail := runes[len(runes)-limit:]
+	for i, r := range tail {
+		if unicode.IsSpace(r) {
+			return strings.TrimSpace(string(tail[i:]))
+		}
+	}
+	return string(tail)
+}
+
+// plainSections é o caso sem estrutura (PDF, txt): um bloco só, quebrado pelo splitIntoChunks.
+func plainSections(content string) []section {
+	return []section{{blocks: []string{content}}}
+}
+
+var mdHeadingRe = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+
+// splitMarkdown quebra Markdown nos headings (#, ##, ...), mantendo
+// blocos de código (``` / ~~~) e tabelas (linhas com |) inteiros.
+func splitMarkdown(content string) []section {
+	var sections []section
+	var stack []string // títulos por nível
+
+	cur := section{}
+	var block []string
+	var fence string // marcador do code fence aberto ("```" ou "~~~")
+	inTable := false
+
+	flushBlock := func() {
+		if len(block) > 0 {
+			cur.blocks = append(cur.blocks, strings.Join(block, "\n"))
+		}
+		block = nil
+		inTable = false
+	}
+	flushSection := func() {
+		flushBlock()
+		if len(cur.blocks) > 0 {
+			sections = append(sections, cur)
+		}
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if fence != "" {
+			block = append(block, line)
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+				flushBlock()
+			}
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			flushBlock()
+			fence = trimmed[:3]
+			block = append(block, line)
+			continue
+		}
+
+		if m := mdHeadingRe.FindStringSubmatch(trimmed); m != nil {
+			flushSection()
+			level := len(m[1])
+			if len(stack) >= level {
+				stack = stack[:level-1]
+			}
+			for len(stack) < level-1 {
+				stack = append(stack, "")
+			}
+			stack = append(stack, m[2])
+			cur = section{breadcrumb: compact(stack)}
+			continue
+		}
+
+		if trimmed == "" {
+			flushBlock()
+			continue
+		}
+
+		isTableLine := strings.HasPrefix(trimmed, "|")
+		if isTableLine != inTable {
+			flushBlock()
+			inTable = isTableLine
+		}
+		block = append(block, line)
+	}
+	flushSection()
+
+	return sections
+}
+
+// splitHTML quebra HTML nos headings h1–h4. Blocos <pre> e <table> viram
+// um bloco único (tabela renderizada como Markdown); o resto do texto é agrupado por elemento de bloco.
+func splitHTML(htmlStr string) []section {
+	doc, err := html.Parse(strings.NewReader(htmlStr))
+	if err != nil {
+		return nil
+	}
+
+	var sections []section
+	var stack []string
+
+	cur := section{}
+	var text strings.Builder
+
+	flushText := func() {
+		t := strings.TrimSpace(text.String())
+		if len(t) > 1 {
+			cur.blocks = append(cur.blocks, t)
+		}
+		text.Reset()
+	}
+	flushSection := func() {
+		flushText()
+		if len(cur.blocks) > 0 {
+			sections = append(sections, cur)
+		}
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script", "style", "noscript":
+				return
+
+			case "h1", "h2", "h3", "h4":
+				title := strings.Join(strings.Fields(nodeText(n)), " ")
+				if title == "" {
+					return
+				}
+				flushSection()
+				level := int(n.Data[1] - '0')
+				if len(stack) >= level {
+					stack = stack[:level-1]
+				}
+				for len(stack) < level-1 {
+					stack = append(stack, "")
+				}
+				stack = append(stack, title)
+				cur = section{breadcrumb: compact(stack)}
+				return
+
+			case "pre":
+				flushText()
+				if code := strings.TrimRight(nodeText(n), "\n "); strings.TrimSpace(code) != "" {
+					cur.blocks = append(cur.blocks, "```\n"+code+"\n```")
+				}
+				return
+
+			case "table":
+				flushText()
+				if t := renderTable(n); t != "" {
+					cur.blocks = append(cur.blocks, t)
+				}
+				return
+
+			case "p", "div", "section", "article", "li", "ul", "ol", "dl", "dt", "dd",
+				"blockquote", "h5", "h6", "br", "header", "footer", "main", "nav":
+				flushText()
+				if n.Data == "li" {
+					text.WriteString("- ")
+				}
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					walk(c)
+				}
+				flushText()
+				return
+			}
+		}
+
+		if n.Type == html.TextNode {
+			if t := strings.Join(strings.Fields(n.Data), " "); t != "" {
+				if text.Len() > 0 && !strings.HasSuffix(text.String(), " ") {
+					text.WriteString(" ")
+				}
+				text.WriteString(t)
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	flushSection()
+
+	return sections
+}
+
+// renderTable converte uma <table> em tabela Markdown, linha a linha.
+func renderTable(table *html.Node) string {
+	var rows []string
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "tr" {
+			var cells []string
+			header := false
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type != html.ElementNode || (c.Data != "td" && c.Data != "th") {
+					continue
+				}
+				if c.Data == "th" {
+					header = true
+				}
+				cell := strings.Join(strings.Fields(nodeText(c)), " ")
+				cells = append(cells, strings.ReplaceAll(cell, "|", "\\|"))
+			}
+			if len(cells) == 0 {
+				return
+			}
+			rows = append(rows, "| "+strings.Join(cells, " | ")+" |")
+			if header && len(rows) == 1 {
+				rows = append(rows, "|"+strings.Repeat(" --- |", len(cells)))
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(table)
+
+	return strings.Join(rows, "\n")
+}
+
+// nodeText concatena o texto de um nó, preservando quebras de linha (útil para <pre>).
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		if n.Type == html.ElementNode && n.Data == "br" {
+			b.WriteString("\n")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// compact remove níveis vazios do breadcrumb (ex: doc que pula de h1 para h3).
+func compact(stack []string) []string {
+	out := make([]string, 0, len(stack))
+	for _, s := range stack {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+)
+
+func TestPackSectionsSplitTable(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("## Códigos de retorno\n\n| Código | Mensagem |\n| --- | --- |\n")
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&b, "| %d | Transação não autorizada pelo emissor, motivo %d |\n", i, i)
+	}
+
+	pieces := packSections("e-Rede", splitMarkdown(b.String()), chunkOptions{maxTokens: 500, overlapTokens: 50})
+	if len(pieces) < 2 {
+		t.Fatalf("pieces = %d, want the table split", len(pieces))
+	}
+
+	codes := make(map[string]bool)
+	for i, p := range pieces {
+		if !strings.HasPrefix(p.Content, "Códigos de retorno\n\n| Código | Mensagem |\n| --- | --- |\n") {
+			t.Errorf("piece %d does not start with the table header: %q", i, clip(p.Content, 80))
+		}
+		if approxTokens(p.Content) > 500 {
+			t.Errorf("piece %d has %d tokens", i, approxTokens(p.Content))
+		}
+		for _, e := range rag.ExtractErrorCodes(p.Content) {
+			codes[e.Code] = true
+		}
+	}
+	if len(codes) != 100 {
+		t.Errorf("codes extracted = %d, want 100", len(codes))
+	}
+}
+
+func clip(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+func TestSplitMarkdown(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want []section
+	}{
+		{
+			name: "headings viram breadcrumb",
+			in:   "# API\nintro\n\n## Estorno\ntexto do estorno\n\n### Parcial\nparcial\n\n## Captura\ncaptura",
+			want: []section{
+				{breadcrumb: []string{"API"}, blocks: []string{"intro"}},
+				{breadcrumb: []string{"API", "Estorno"}, blocks: []string{"texto do estorno"}},
+				{breadcrumb: []string{"API", "Estorno", "Parcial"}, blocks: []string{"parcial"}},
+				{breadcrumb: []string{"API", "Captura"}, blocks: []string{"captura"}},
+			},
+		},
+		{
+			name: "nível pulado não deixa título vazio",
+			in:   "# API\n### Detalhe\ntexto",
+			want: []section{{breadcrumb: []string{"API", "Detalhe"}, blocks: []string{"texto"}}},
+		},
+		{
+			name: "code fence e tabela ficam inteiros",
+			in:   "## Exemplo\nantes\n```json\n{\n\n# não é heading\n}\n```\n| a | b |\n| --- | --- |\n| 1 | 2 |\ndepois",
+			want: []section{{breadcrumb: []string{"Exemplo"}, blocks: []string{
+				"antes",
+				"```json\n{\n\n# não é heading\n}\n```",
+				"| a | b |\n| --- | --- |\n| 1 | 2 |",
+				"depois",
+			}}},
+		},
+		{
+			name: "texto antes do primeiro heading",
+			in:   "solto\n\n# Título\ncorpo",
+			want: []section{
+				{blocks: []string{"solto"}},
+				{breadcrumb: []string{"Título"}, blocks: []string{"corpo"}},
+			},
+		},
+	}
+	for _, tc := range cases {
+		if got := splitMarkdown(tc.in); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %#v, want %#v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSplitHTML(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want []section
+	}{
+		{
+			name: "headings, parágrafos e lista",
+			in:   "<h1>API</h1><p>intro <b>forte</b></p><h2>Estorno</h2><ul><li>um</li><li>dois</li></ul><script>x()</script>",
+			want: []section{
+				{breadcrumb: []string{"API"}, blocks: []string{"intro forte"}},
+				{breadcrumb: []string{"API", "Estorno"}, blocks: []string{"- um", "- dois"}},
+			},
+		},
+		{
+			name: "pre vira code fence e table vira Markdown",
+			in:   "<h2>Erros</h2><pre>{\n  \"a\": 1\n}</pre><table><tr><th>código</th><th>mensagem</th></tr><tr><td>58</td><td>não | permitida</td></tr></table>",
+			want: []section{{breadcrumb: []string{"Erros"}, blocks: []string{
+				"```\n{\n  \"a\": 1\n}\n```",
+				"| código | mensagem |\n| --- | --- |\n| 58 | não \\| permitida |",
+			}}},
+		},
+	}
+	for _, tc := range cases {
+		if got := splitHTML(tc.in); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %#v, want %#v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestPackSections(t *testing.T) {
+	long := strings.Repeat("palavra ", 30) // 240 runas, 60 tokens
+	cases := []struct {
+		name      string
+		sections  []section
+		opts      chunkOptions
+		titles    []string
+		maxTokens int
+	}{
+		{
+			name:     "seção pequena vira um chunk",
+			sections: []section{{breadcrumb: []string{"A", "B"}, blocks: []string{"um", "dois"}}},
+			opts:     chunkOptions{maxTokens: 100},
+			titles:   []string{"Doc > A > B"},
+		},
+		{
+			name:     "seção grande é agrupada por blocos",
+			sections: []section{{breadcrumb: []string{"A"}, blocks: []string{long, long, long}}},
+			opts:     chunkOptions{maxTokens: 130, overlapTokens: 10},
+			titles:   []string{"Doc > A (parte 1)", "Doc > A (parte 2)", "Doc > A (parte 3)"},
+		},
+		{
+			name:     "bloco maior que o limite cai no corte por linhas",
+			sections: []section{{blocks: []string{strings.Repeat(long+"\n", 4)}}},
+			opts:     chunkOptions{maxTokens: 70},
+			titles:   []string{"Doc (parte 1)", "Doc (parte 2)", "Doc (parte 3)", "Doc (parte 4)"},
+		},
+	}
+	for _, tc := range cases {
+		pieces := packSections("Doc", tc.sections, tc.opts)
+		var titles []string
+		for i, p := range pieces {
+			titles = append(titles, p.Title)
+			if approxTokens(p.Content) > tc.opts.maxTokens {
+				t.Errorf("%s: piece %d has %d tokens, max %d", tc.name, i, approxTokens(p.Content), tc.opts.maxTokens)
+			}
+		}
+		if !reflect.DeepEqual(titles, tc.titles) {
+			t.Errorf("%s: titles = %q, want %q", tc.name, titles, tc.titles)
+		}
+	}
+
+	// o breadcrumb abre o chunk e a parte seguinte começa com o fim da anterior
+	pieces := packSections("Doc", []section{{breadcrumb: []string{"A"}, blocks: []string{"primeiro bloco termina aqui", "segundo"}}}, chunkOptions{maxTokens: 11, overlapTokens: 2})
+	if len(pieces) != 2 || !strings.HasPrefix(pieces[0].Content, "A\n\nprimeiro") || pieces[1].Content != "A\n\naqui\nsegundo" {
+		t.Errorf("overlap: %#v", pieces)
+	}
+}
+
+func TestTailTokens(t *testing.T) {
+	cases := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"curto", 5, "curto"},
+		{"uma frase com várias palavras", 3, "palavras"}, // 12 runas ("ias palavras"), começa depois do espaço
+		{"ação ação ação ação", 2, "ação"},               // conta runas, não bytes
+		{"semespaçonenhumaqui", 1, "aqui"},               // sem espaço, corta seco
+		{"linha um\nlinha dois", 3, "linha dois"},        // quebra de linha também é fronteira
+	}
+	for _, tc := range cases {
+		if got := tailTokens(tc.in, tc.n); got != tc.want {
+			t.Errorf("tailTokens(%q, %d) = %q, want %q", tc.in, tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestCutRunes(t *testing.T) {
+	cases := []struct {
+		in         string
+		n          int
+		head, rest string
+	}{
+		{"cabe inteiro", 20, "cabe inteiro", ""},
+		{"corta no espaço antes do limite", 12, "corta no", "espaço antes do limite"},
+		{"ééééééééé", 4, "éééé", "ééééé"}, // sem espaço: corta em runas
+		{"café com leite", 6, "café", "com leite"},
+		{"aaaaaaaaaa bb", 10, "aaaaaaaaaa", "bb"},
+	}
+	for _, tc := range cases {
+		head, rest := cutRunes(tc.in, tc.n)
+		if head != tc.head || rest != tc.rest {
+			t.Errorf("cutRunes(%q, %d) = %q, %q; want %q, %q", tc.in, tc.n, head, rest, tc.head, tc.rest)
+		}
+		if !utf8.ValidString(head) || !utf8.ValidString(rest) {
+			t.Errorf("cutRunes(%q, %d) split a rune", tc.in, tc.n)
+		}
+	}
+}
+
+func TestSplitIntoChunksRuneSafe(t *testing.T) {
+	// linha única bem maior que o limite, só com caracteres de 2 e 3 bytes
+	line := strings.Repeat("ação€ ", 200)
+	chunks := splitIntoChunks(line, 50)
+	if len(chunks) < 2 {
+		t.Fatalf("chunks = %d, want the line split", len(chunks))
+	}
+	var joined []string
+	for i, c := range chunks {
+		if !utf8.ValidString(c) {
+			t.Errorf("chunk %d is not valid UTF-8", i)
+		}
+		if approxTokens(c) > 50 {
+			t.Errorf("chunk %d has %d tokens", i, approxTokens(c))
+		}
+		joined = append(joined, c)
+	}
+	if got := strings.Join(strings.Fields(strings.Join(joined, " ")), " "); got != strings.TrimSpace(line) {
+		t.Error("text lost or changed when splitting")
+	}
+
+	// bytes inválidos somem em vez de virar chunk quebrado
+	if got := splitIntoChunks("a\xffb", 10); !reflect.DeepEqual(got, []string{"ab"}) {
+		t.Errorf("invalid UTF-8: %q", got)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/llm"
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+)
+
+// embedPool gera embeddings em lotes (EmbedBatch) com N workers em paralelo,
+// respeitando um limite de requests por minuto e fazendo backoff exponencial
+// em erros retentáveis (429, 5xx).
+type embedPool struct {
+	client     rag.EmbeddingsClient
+	workers    int
+	batchSize  int
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	limiter    *rateLimiter
+}
+
+func newEmbedPool(client rag.EmbeddingsClient, workers, batchSize, rpm, maxRetries int) *embedPool {
+	if workers <= 0 {
+		workers = 1
+	}
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	return &embedPool{
+		client:     client,
+		workers:    workers,
+		batchSize:  batchSize,
+		maxRetries: maxRetries,
+		baseDelay:  time.Second,
+		maxDelay:   30 * time.Second,
+		limiter:    newRateLimiter(rpm),
+	}
+}
+
+// embedAll devolve um embedding (ou um erro) por texto, na mesma ordem de texts.
+func (p *embedPool) embedAll(ctx context.Context, texts []string) ([][]float32, []error) {
+	vecs := make([][]float32, len(texts))
+	errs := make([]error, len(texts))
+
+	// cada job é o índice inicial de um lote
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	batches := (len(texts) + p.batchSize - 1) / p.batchSize
+	workers := min(p.workers, batches)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range jobs {
+				end := min(start+p.batchSize, len(texts))
+				p.embedBatch(ctx, texts[start:end], vecs[start:end], errs[start:end])
+			}
+		}()
+	}
+
+	for start := 0; start < len(texts); start += p.batchSize {
+		jobs <- start
+	}
+	close(jobs)
+	wg.Wait()
+
+	return vecs, errs
+}
+
+// embedBatch preenche vecs/errs de um lote. Se o lote falhar com erro não
+// retentável (ex: um texto inválido), refaz texto a texto para isolar o culpado.
+func (p *embedPool) embedBatch(ctx context.Context, texts []string, vecs [][]float32, errs []error) {
+	var out [][]float32
+	err := p.withRetry(ctx, func() error {
+		var err error
+		out, err = p.client.EmbedBatch(ctx, texts)
+		return err
+	})
+	if err == nil {
+		copy(vecs, out)
+		return
+	}
+
+	if len(texts) == 1 || llm.IsRetryable(err) || ctx.Err() != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return
+	}
+
+	for i, t := range texts {
+		errs[i] = p.withRetry(ctx, func() error {
+			var err error
+			vecs[i], err = p.client.Embed(ctx, t)
+			return err
+		})
+	}
+}
+
+// withRetry chama fn até dar certo, até maxRetries tentativas extras ou até um erro não retentável.
+// Toda tentativa passa pelo rate limiter.
+func (p *embedPool) withRetry(ctx context.Context, fn func() error) error {
+	var lastErr error
+	for attempt := 0; attempt <= p.maxRetries; attempt++ {
+		if attempt > 0 {
+			delay := p.backoff(attempt)
+			log.Printf("↻ retry embedding em %s (tentativa %d/%d): %v", delay, attempt, p.maxRetries, lastErr)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if err := p.limiter.wait(ctx); err != nil {
+			return err
+		}
+
+		lastErr = fn()
+		if lastErr == nil || !llm.IsRetryable(lastErr) {
+			return lastErr
+		}
+	}
+	return lastErr
+}
+
+// backoff: baseDelay * 2^(attempt-1), com teto em maxDelay e ±25% de jitter
+// para os workers não voltarem todos juntos no provider.
+func (p *embedPool) backoff(attempt int) time.Duration {
+	d := p.baseDelay << (attempt - 1)
+	if d <= 0 || d > p.maxDelay {
+		d = p.maxDelay
+	}
+	jitter := time.Duration(rand.Int64N(int64(d)/2+1)) - d/4
+	return d + jitter
+}
+
+// rateLimiter libera no máximo rpm chamadas por minuto, espaçadas uniformemente.
+// nil significa sem limite.
+type rateLimiter struct {
+	ticker *time.Ticker
+}
+
+func newRateLimiter(rpm int) *rateLimiter {
+	if rpm <= 0 {
+		return nil
+	}
+	return &rateLimiter{ticker: time.NewTicker(time.Minute / time.Duration(rpm))}
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case <-l.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// embedFailure é um chunk que não conseguiu embedding mesmo depois dos retries.
+type embedFailure struct {
+	Source string
+	Index  int
+	Title  string
+	Err    error
+}
+
+func (f embedFailure) String() string {
+	return fmt.Sprintf("%s #%d (%s): %v", f.Source, f.Index+1, f.Title, f.Err)
+}
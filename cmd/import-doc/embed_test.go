@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/llm"
+)
+
+var (
+	errRateLimited = &llm.HTTPError{StatusCode: http.StatusTooManyRequests, Body: "rate limit"}
+	errBadInput    = &llm.HTTPError{StatusCode: http.StatusBadRequest, Body: "invalid input"}
+)
+
+// stubEmbeddings devolve o tamanho do texto como embedding. batchErrs e embedErrs são
+// consumidos a cada chamada (nil = sucesso); textos com "bad" sempre falham no Embed.
+type stubEmbeddings struct {
+	mu         sync.Mutex
+	batchErrs  []error
+	embedErrs  []error
+	batchCalls int
+	embedCalls int
+}
+
+func (s *stubEmbeddings) Embed(_ context.Context, text string) ([]float32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.embedCalls++
+	if strings.Contains(text, "bad") {
+		return nil, errBadInput
+	}
+	if len(s.embedErrs) > 0 {
+		err := s.embedErrs[0]
+		s.embedErrs = s.embedErrs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return []float32{float32(len(text))}, nil
+}
+
+func (s *stubEmbeddings) EmbedBatch(_ context.Context, texts []string) ([][]float32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batchCalls++
+	if len(s.batchErrs) > 0 {
+		err := s.batchErrs[0]
+		s.batchErrs = s.batchErrs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		if strings.Contains(t, "bad") {
+			return nil, errBadInput
+		}
+		out[i] = []float32{float32(len(t))}
+	}
+	return out, nil
+}
+
+// newTestPool é o embedPool com backoff de milissegundos.
+func newTestPool(client *stubEmbeddings, workers, batchSize, maxRetries int) *embedPool {
+	p := newEmbedPool(client, workers, batchSize, 0, maxRetries)
+	p.baseDelay, p.maxDelay = time.Millisecond, 4*time.Millisecond
+	return p
+}
+
+func TestWithRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      []error
+		wantErr   error
+		wantCalls int
+	}{
+		{"first try", []error{nil}, nil, 1},
+		{"retryable then ok", []error{errRateLimited, errRateLimited, nil}, nil, 3},
+		{"not retryable", []error{errBadInput, nil}, errBadInput, 1},
+		{"retries exhausted", []error{errRateLimited, errRateLimited, errRateLimited, errRateLimited}, errRateLimited, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPool(&stubEmbeddings{}, 1, 1, 2)
+			calls := 0
+			err := p.withRetry(context.Background(), func() error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			})
+			if !errors.Is(err, tt.wantErr) || calls != tt.wantCalls {
+				t.Errorf("err = %v, calls = %d, want %v and %d", err, calls, tt.wantErr, tt.wantCalls)
+			}
+		})
+	}
+
+	// cancelamento durante o backoff para na hora
+	p := newTestPool(&stubEmbeddings{}, 1, 1, 5)
+	p.baseDelay, p.maxDelay = time.Hour, time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := p.withRetry(ctx, func() error {
+		calls++
+		cancel()
+		return errRateLimited
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("canceled: err = %v, calls = %d", err, calls)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := newEmbedPool(&stubEmbeddings{}, 1, 1, 0, 0)
+	p.baseDelay, p.maxDelay = 100*time.Millisecond, time.Second
+
+	for attempt, base := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 5: time.Second, 40: time.Second} {
+		for range 50 {
+			d := p.backoff(attempt)
+			if d < base*3/4 || d > base*5/4 {
+				t.Fatalf("backoff(%d) = %s, want %s ±25%%", attempt, d, base)
+			}
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	var unlimited *rateLimiter
+	if err := unlimited.wait(context.Background()); err != nil || newRateLimiter(0) != nil {
+		t.Fatalf("rpm 0 must not limit: %v", err)
+	}
+
+	// 6000 por minuto = uma chamada a cada 10ms
+	l := newRateLimiter(6000)
+	start := time.Now()
+	for range 3 {
+		if err := l.wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Errorf("3 calls took %s, want >= 30ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := newRateLimiter(1).wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled wait = %v", err)
+	}
+}
+
+func TestEmbedAll(t *testing.T) {
+	client := &stubEmbeddings{batchErrs: []error{errRateLimited}}
+	texts := []string{"a", "bb", "ccc", "dddd", "eeeee"}
+	vecs, errs := newTestPool(client, 3, 2, 2).embedAll(context.Background(), texts)
+	for i := range texts {
+		if errs[i] != nil || len(vecs[i]) != 1 || vecs[i][0] != float32(len(texts[i])) {
+			t.Errorf("texts[%d]: vec = %v, err = %v", i, vecs[i], errs[i])
+		}
+	}
+	// 3 lotes + 1 retry do 429
+	if client.batchCalls != 4 || client.embedCalls != 0 {
+		t.Errorf("batch calls = %d, embed calls = %d", client.batchCalls, client.embedCalls)
+	}
+}
+
+func TestEmbedBatchFallsBackToSingleTexts(t *testing.T) {
+	// erro não retentável no lote: refaz texto a texto e só o inválido falha
+	client := &stubEmbeddings{embedErrs: []error{errRateLimited}}
+	texts := []string{"ok", "bad text", "fine"}
+	vecs := make([][]float32, len(texts))
+	errs := make([]error, len(texts))
+	newTestPool(client, 1, 3, 2).embedBatch(context.Background(), texts, vecs, errs)
+
+	if !errors.Is(errs[1], errBadInput) || errs[0] != nil || errs[2] != nil {
+		t.Fatalf("errs = %v", errs)
+	}
+	if vecs[0][0] != 2 || vecs[1] != nil || vecs[2][0] != 4 {
+		t.Errorf("vecs = %v", vecs)
+	}
+	// o 429 do primeiro Embed também passa pelo retry
+	if client.batchCalls != 1 || client.embedCalls != 4 {
+		t.Errorf("batch calls = %d, embed calls = %d", client.batchCalls, client.embedCalls)
+	}
+
+	// erro retentável que esgota os retries: o lote inteiro falha, sem fallback
+	client = &stubEmbeddings{batchErrs: []error{errRateLimited, errRateLimited, errRateLimited}}
+	errs = make([]error, 2)
+	newTestPool(client, 1, 2, 2).embedBatch(context.Background(), []string{"a", "b"}, make([][]float32, 2), errs)
+	if !errors.Is(errs[0], errRateLimited) || !errors.Is(errs[1], errRateLimited) || client.embedCalls != 0 {
+		t.Errorf("errs = %v, embed calls = %d", errs, client.embedCalls)
+	}
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"io"
@@ -13,6 +15,7 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode"
 	"unicode/utf8"
 
 	pdf "github.com/dslipak/pdf"
@@ -33,60 +36,161 @@ func main() {
 	fromURL := flag.Bool("from-url", false, "importar via crawl HTTP")
 	baseURLFlag := flag.String("base-url", "", "URL base para crawl (ex: https://developer.userede.com.br/e-rede)")
 	maxPagesFlag := flag.Int("max-pages", 50, "limite de páginas para crawl HTTP")
+	fromOpenAPI := flag.String("from-openapi", "", "importar spec OpenAPI 3 / Swagger 2 (JSON ou YAML, arquivo ou URL), um chunk por operação")
+	fromPostman := flag.String("from-postman", "", "importar collection Postman v2.1 (arquivo ou URL), um chunk por request")
 	apiVersionFlag := flag.String("api-version", "", "versão da API (opcional)")
+	pruneFlag := flag.Bool("prune", false, "remove documentos do provider que não existem mais na origem (arquivos/URLs)")
+	embedWorkersFlag := flag.Int("embed-workers", 4, "quantidade de chamadas de embedding em paralelo")
+	embedBatchFlag := flag.Int("embed-batch", 100, "textos por request de embedding (EmbedBatch)")
+	embedRPMFlag := flag.Int("embed-rpm", 0, "limite de requests de embedding por minuto (0 = sem limite)")
+	embedRetriesFlag := flag.Int("embed-retries", 5, "retries com backoff exponencial em erros retentáveis (429, 5xx)")
+	chunkTokensFlag := flag.Int("chunk-tokens", 500, "tamanho máximo do chunk em tokens aproximados (~4 caracteres/token)")
+	chunkOverlapFlag := flag.Int("chunk-overlap", 50, "tokens repetidos entre chunks consecutivos da mesma seção")
 	flag.Parse()
 
 	if *providerFlag == "" {
 		log.Fatal("obrigatório: --provider")
 	}
-	provider := rag.Provider(*providerFlag)
 
-	if !*fromFiles && !*fromURL {
-		log.Fatal("use pelo menos um modo: --from-files ou --from-url")
+	if !*fromFiles && !*fromURL && *fromOpenAPI == "" && *fromPostman == "" {
+		log.Fatal("use pelo menos um modo: --from-files, --from-url, --from-openapi ou --from-postman")
+	}
+
+	if *chunkTokensFlag <= 0 || *chunkOverlapFlag < 0 || *chunkOverlapFlag*2 > *chunkTokensFlag {
+		log.Fatal("--chunk-tokens deve ser > 0 e --chunk-overlap no máximo metade dele")
 	}
 
 	ctx := context.Background()
 	cfg := config.Load()
-	pool := db.NewPool(cfg.DatabaseURL)
-	defer pool.Close()
+	if cfg.Storage == "memory" && cfg.MemoryStorePath == "" {
+		log.Fatal("STORAGE=memory no importador exige MEMORY_STORE_PATH (senão os chunks se perdem ao sair)")
+	}
+	repo, closeStore, err := db.NewStore(cfg)
+	if err != nil {
+		log.Fatalf("erro ao abrir o storage: %v", err)
+	}
+	defer closeStore()
 
-	repo := rag.NewPgRepository(pool)
+	// o provider precisa estar cadastrado (tabela provider); aceita slug ou apelido
+	providers, err := repo.ListProviders(ctx)
+	if err != nil {
+		log.Fatalf("erro ao listar providers: %v", err)
+	}
+	info := rag.LookupProvider(providers, *providerFlag)
+	if info == nil {
+		log.Fatalf("provider %q não cadastrado: insira na tabela provider (migration 005) antes de importar", *providerFlag)
+	}
+	if !info.Enabled {
+		log.Printf("⚠️  provider %s está desabilitado: os chunks não aparecem na API até habilitar", info.Slug)
+	}
+	provider := info.Slug
 
-	geminiClient, err := llm.NewGeminiClient(ctx)
+	backend, err := llm.NewBackend(ctx, cfg)
 	if err != nil {
-		log.Fatalf("erro ao iniciar Gemini: %v", err)
+		log.Fatalf("erro ao iniciar backend de LLM: %v", err)
+	}
+
+	imp := &importer{
+		repo:       repo,
+		embed:      newEmbedPool(backend.Embeddings, *embedWorkersFlag, *embedBatchFlag, *embedRPMFlag, *embedRetriesFlag),
+		provider:   provider,
+		apiVersion: *apiVersionFlag,
+
+		defaultAPIVersion: info.DefaultAPIVersion,
+		chunking: chunkOptions{
+			maxTokens:     *chunkTokensFlag,
+			overlapTokens: *chunkOverlapFlag,
+		},
 	}
 
 	if *fromFiles {
 		if *pathFlag == "" {
 			log.Fatal("--path é obrigatório com --from-files")
 		}
-		if err := importFromFiles(ctx, repo, geminiClient, provider, *pathFlag, *apiVersionFlag); err != nil {
+		// o WalkDir devolve os filhos com path limpo ("./docs/x" vira "docs/x"): limpa a raiz
+		// também, para o source e o prefixo do --prune baterem
+		root := filepath.Clean(*pathFlag)
+		imp.seen = make(map[string]bool)
+		imp.partial = false
+		if err := imp.importFromFiles(ctx, root); err != nil {
 			log.Fatalf("erro importando arquivos: %v", err)
 		}
+		if *pruneFlag {
+			imp.prune(ctx, root)
+		}
 	}
 
 	if *fromURL {
 		if *baseURLFlag == "" {
 			log.Fatal("--base-url é obrigatório com --from-url")
 		}
-		if err := importFromHTTP(ctx, repo, geminiClient, provider, *baseURLFlag, *apiVersionFlag, *maxPagesFlag); err != nil {
+		imp.seen = make(map[string]bool)
+		imp.partial = false
+		if err := imp.importFromHTTP(ctx, *baseURLFlag, *maxPagesFlag); err != nil {
 			log.Fatalf("erro importando HTTP: %v", err)
 		}
+		if *pruneFlag {
+			imp.prune(ctx, *baseURLFlag)
+		}
+	}
+
+	if *fromOpenAPI != "" {
+		imp.seen = nil
+		if err := imp.importFromOpenAPI(ctx, *fromOpenAPI); err != nil {
+			log.Fatalf("erro importando OpenAPI: %v", err)
+		}
+	}
+
+	if *fromPostman != "" {
+		imp.seen = nil
+		if err := imp.importFromPostman(ctx, *fromPostman); err != nil {
+			log.Fatalf("erro importando Postman: %v", err)
+		}
+	}
+
+	if len(imp.failures) > 0 {
+		log.Printf("⚠️  %d chunk(s) sem embedding depois dos retries:", len(imp.failures))
+		for _, f := range imp.failures {
+			log.Printf("   - %s", f)
+		}
+		log.Println("Os documentos acima foram salvos sem esses chunks e marcados para reimport: rodando o mesmo comando de novo, cada um deles é reprocessado inteiro (todos os chunks são embedados e substituídos de novo); os demais são pulados.")
+		os.Exit(1)
 	}
 
 	log.Println("✅ Importação concluída.")
 }
 
-func importFromFiles(
-	ctx context.Context,
-	repo *rag.PgRepository,
-	gemini *llm.GeminiClient,
-	provider rag.Provider,
-	rootPath string,
-	apiVersion string,
-) error {
-	log.Printf("📂 Importando docs locais de %s para provider=%s", rootPath, provider)
+// importer carrega o que é comum a todos os modos de importação.
+type importer struct {
+	repo       rag.DocumentRepository
+	embed      *embedPool
+	provider   rag.Provider
+	apiVersion string
+	chunking   chunkOptions
+
+	// defaultAPIVersion vem do cadastro do provider; vale quando não há --api-version
+	// (nem versão declarada na spec OpenAPI).
+	defaultAPIVersion string
+
+	// seen guarda os sources vistos no modo atual (inclusive os inalterados), para o --prune.
+	seen map[string]bool
+	// partial marca que o modo atual não viu a origem inteira (página que falhou, crawl
+	// parado no --max-pages): o que não está em seen pode só não ter sido visitado.
+	partial bool
+
+	// failures acumula os chunks que falharam no embedding, para o resumo final.
+	failures []embedFailure
+}
+
+func (imp *importer) version() string {
+	if imp.apiVersion != "" {
+		return imp.apiVersion
+	}
+	return imp.defaultAPIVersion
+}
+
+func (imp *importer) importFromFiles(ctx context.Context, rootPath string) error {
+	log.Printf("📂 Importando docs locais de %s para provider=%s", rootPath, imp.provider)
 
 	return filepath.WalkDir(rootPath, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
@@ -101,6 +205,7 @@ func importFromFiles(
 
 		lpath := strings.ToLower(path)
 		var content string
+		var sections func(string) []section
 
 		switch {
 		case strings.HasSuffix(lpath, ".pdf"):
@@ -109,20 +214,31 @@ func importFromFiles(
 				return fmt.Errorf("erro lendo pdf %s: %w", path, err)
 			}
 			content = text
+			sections = plainSections
 
 		case strings.HasSuffix(lpath, ".html") || strings.HasSuffix(lpath, ".htm"):
 			data, err := os.ReadFile(path)
 			if err != nil {
 				return fmt.Errorf("erro lendo %s: %w", path, err)
 			}
-			content = extractMainText(string(data))
+			content = string(data)
+			sections = splitHTML
+
+		case strings.HasSuffix(lpath, ".md"):
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("erro lendo %s: %w", path, err)
+			}
+			content = string(data)
+			sections = splitMarkdown
 
-		default: 
+		default:
 			data, err := os.ReadFile(path)
 			if err != nil {
 				return fmt.Errorf("erro lendo %s: %w", path, err)
 			}
 			content = string(data)
+			sections = plainSections
 		}
 
 		content = strings.TrimSpace(content)
@@ -132,19 +248,13 @@ func importFromFiles(
 		}
 
 		title := filenameToTitle(path)
-		return chunkAndStore(ctx, repo, gemini, provider, title, "", apiVersion, content)
+		pieces := packSections(title, sections(content), imp.chunking)
+		return imp.importDocument(ctx, path, "", content, pieces)
 	})
 }
 
-func importFromHTTP(
-	ctx context.Context,
-	repo *rag.PgRepository,
-	gemini *llm.GeminiClient,
-	provider rag.Provider,
-	baseURL, apiVersion string,
-	maxPages int,
-) error {
-	log.Printf("🌐 Crawl HTTP: base=%s provider=%s maxPages=%d", baseURL, provider, maxPages)
+func (imp *importer) importFromHTTP(ctx context.Context, baseURL string, maxPages int) error {
+	log.Printf("🌐 Crawl HTTP: base=%s provider=%s maxPages=%d", baseURL, imp.provider, maxPages)
 
 	base, err := url.Parse(baseURL)
 	if err != nil {
@@ -169,11 +279,16 @@ func importFromHTTP(
 		resp, err := http.Get(current)
 		if err != nil {
 			log.Printf("erro GET %s: %v", current, err)
+			imp.partial = true
 			continue
 		}
 		if resp.StatusCode != http.StatusOK {
 			log.Printf("status %d em %s", resp.StatusCode, current)
 			resp.Body.Close()
+			// 404/410 é página que saiu da doc (o --prune pode remover); o resto é falha
+			if resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusGone {
+				imp.partial = true
+			}
 			continue
 		}
 
@@ -181,16 +296,17 @@ func importFromHTTP(
 		resp.Body.Close()
 		if err != nil {
 			log.Printf("erro lendo body %s: %v", current, err)
+			imp.partial = true
 			continue
 		}
 
-		htmlStr := string(bodyBytes)
-		text := extractMainText(htmlStr)
-		text = strings.TrimSpace(text)
-		text = sanitizeUTF8(text)
+		htmlStr := sanitizeUTF8(string(bodyBytes))
+		text := strings.TrimSpace(extractMainText(htmlStr))
 		if text != "" {
 			title := urlToTitle(current, base)
-			if err := chunkAndStore(ctx, repo, gemini, provider, title, current, apiVersion, text); err != nil {
+			pieces := packSections(title, splitHTML(htmlStr), imp.chunking)
+			// hash sobre o texto extraído: mudanças só de markup/scripts não forçam reimport
+			if err := imp.importDocument(ctx, current, current, text, pieces); err != nil {
 				log.Printf("erro salvando chunks de %s: %v", current, err)
 			}
 		}
@@ -202,6 +318,14 @@ func importFromHTTP(
 		}
 	}
 
+	for _, link := range queue {
+		if !visited[link] {
+			log.Printf("⚠️  limite de --max-pages (%d) atingido com páginas na fila", maxPages)
+			imp.partial = true
+			break
+		}
+	}
+
 	return nil
 }
 
@@ -334,67 +458,165 @@ func extractLinks(htmlStr string, base *url.URL) []string {
 	return out
 }
 
-func chunkAndStore(
-	ctx context.Context,
-	repo *rag.PgRepository,
-	gemini *llm.GeminiClient,
-	provider rag.Provider,
-	title, sourceURL, apiVersion, content string,
-) error {
-	const maxLen = 2000
-
-	chunks := splitIntoChunks(content, maxLen)
-	if len(chunks) == 0 {
+// importDocument importa um documento (arquivo ou página) de forma idempotente:
+// se o hash do conteúdo não mudou desde o último import, não faz nada;
+// se mudou, gera os embeddings dos pieces e troca os chunks antigos numa transação.
+func (imp *importer) importDocument(ctx context.Context, source, sourceURL, content string, pieces []piece) error {
+	if imp.seen != nil {
+		imp.seen[source] = true
+	}
+
+	hash := imp.contentHash(content)
+
+	existing, err := imp.repo.GetDocument(ctx, imp.provider, source)
+	if err != nil {
+		return fmt.Errorf("erro buscando documento %s: %w", source, err)
+	}
+	if existing != nil && existing.ContentHash == hash && existing.APIVersion == imp.version() {
+		log.Printf("⏭️  inalterado, pulando: %s", source)
+		return nil
+	}
+
+	docs, vecs, failures := imp.embedPieces(ctx, source, sourceURL, pieces)
+
+	// Com falhas, salva o que deu certo mas sem hash: o próximo import
+	// vê o documento como alterado e tenta de novo.
+	if len(failures) > 0 {
+		hash = ""
+		imp.failures = append(imp.failures, failures...)
+		if len(docs) == 0 {
+			// nada embedado: mantém os chunks antigos (se houver) até o próximo import
+			return nil
+		}
+	}
+	// documento novo sem chunks não tem o que gravar; já um documento que mudou e ficou
+	// sem chunks segue para o ReplaceDocument com lista vazia, que apaga os antigos
+	if len(docs) == 0 && existing == nil {
 		return nil
 	}
 
-	for i, c := range chunks {
-		c = strings.TrimSpace(c)
+	doc := &rag.Document{
+		Provider:    imp.provider,
+		Source:      source,
+		ContentHash: hash,
+		APIVersion:  imp.version(),
+	}
+	id, err := imp.repo.ReplaceDocument(ctx, doc, docs, vecs)
+	if err != nil {
+		return fmt.Errorf("erro salvando documento %s: %w", source, err)
+	}
+
+	log.Printf("✅ documento importado provider=%s id=%d chunks=%d falhas=%d source=%s", imp.provider, id, len(docs), len(failures), source)
+	return nil
+}
+
+// prune remove os documentos sob sourcePrefix que não apareceram neste import.
+// Com o import parcial não dá para saber o que saiu da origem, então não remove nada.
+func (imp *importer) prune(ctx context.Context, sourcePrefix string) {
+	if imp.partial {
+		log.Printf("⚠️  prune de %s pulado: o import não leu a origem inteira (ver erros acima)", sourcePrefix)
+		return
+	}
+
+	keep := make([]string, 0, len(imp.seen))
+	for src := range imp.seen {
+		keep = append(keep, src)
+	}
+
+	n, err := imp.repo.PruneDocuments(ctx, imp.provider, sourcePrefix, keep)
+	if err != nil {
+		log.Printf("erro no prune de %s: %v", sourcePrefix, err)
+		return
+	}
+	log.Printf("🧹 prune: %d documento(s) removido(s) de %s", n, sourcePrefix)
+}
+
+// importFormat é a versão do que o importador grava a partir do mesmo texto: chunker,
+// splitters de seção e extratores de endpoints e códigos de erro. Entra no hash do documento;
+// incremente ao mudar a saída de algum deles, para o próximo import reprocessar tudo.
+const importFormat = 1
+
+// contentHash identifica o que seria gravado: o conteúdo, as opções de chunking e o importFormat.
+// Rodar com outro --chunk-tokens/--chunk-overlap (ou com um importador novo) reprocessa o documento.
+func (imp *importer) contentHash(content string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "format=%d tokens=%d overlap=%d\n", importFormat, imp.chunking.maxTokens, imp.chunking.overlapTokens)
+	h.Write([]byte(content))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (imp *importer) embedPieces(ctx context.Context, source, sourceURL string, pieces []piece) ([]rag.DocChunk, [][]float32, []embedFailure) {
+	var pending []rag.DocChunk
+	var texts []string
+
+	for _, p := range pieces {
+		c := strings.TrimSpace(p.Content)
 		c = sanitizeUTF8(c)
 		if c == "" {
 			continue
 		}
 
-		chunkTitle := title
-		if len(chunks) > 1 {
-			chunkTitle = fmt.Sprintf("%s (parte %d)", title, i+1)
+		sectionType := p.SectionType
+		if sectionType == "" {
+			sectionType = rag.DetectSectionType(c)
+		}
+		tags := p.Tags
+		for _, t := range rag.DetectTags(c) {
+			if !contains(tags, t) {
+				tags = append(tags, t)
+			}
+		}
+
+		var codes []rag.ErrorCode
+		if sectionType == rag.SectionErrors {
+			codes = rag.ExtractErrorCodes(c)
 		}
 
-		doc := &rag.DocChunk{
-			Provider:    provider,
-			SectionType: detectSectionType(c),
-			Title:       chunkTitle,
+		pending = append(pending, rag.DocChunk{
+			Provider:    imp.provider,
+			SectionType: sectionType,
+			Title:       p.Title,
 			Content:     c,
 			SourceURL:   sourceURL,
-			APIVersion:  apiVersion,
-			Tags:        detectTags(c),
+			APIVersion:  imp.version(),
+			Tags:        tags,
+			Endpoints:   rag.ExtractEndpoints(p.Title, c),
+			ErrorCodes:  codes,
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
-		}
+		})
+		texts = append(texts, c)
+	}
 
-		vec, err := gemini.Embed(ctx, c)
-		if err != nil {
-			return fmt.Errorf("embedding error: %w", err)
-		}
+	embeddings, errs := imp.embed.embedAll(ctx, texts)
 
-		id, err := repo.InsertChunk(ctx, doc, vec)
-		if err != nil {
-			return fmt.Errorf("insert chunk error: %w", err)
-		}
+	var docs []rag.DocChunk
+	var vecs [][]float32
+	var failures []embedFailure
 
-		log.Printf("✅ chunk importado provider=%s id=%d len=%d title=%s", provider, id, len(c), chunkTitle)
+	for i, doc := range pending {
+		if errs[i] != nil {
+			failures = append(failures, embedFailure{Source: source, Index: i, Title: doc.Title, Err: errs[i]})
+			continue
+		}
+		docs = append(docs, doc)
+		vecs = append(vecs, embeddings[i])
+		log.Printf("chunk gerado provider=%s len=%d title=%s", imp.provider, len(doc.Content), doc.Title)
 	}
 
-	return nil
+	return docs, vecs, failures
 }
 
-func splitIntoChunks(content string, maxLen int) []string {
+// splitIntoChunks é o corte "burro" por linhas, usado quando não há estrutura
+// (ou a seção é grande demais): junta linhas até maxTokens e quebra linhas
+// maiores que o limite numa fronteira de palavra, sem cortar runas UTF-8.
+func splitIntoChunks(content string, maxTokens int) []string {
 	content = strings.TrimSpace(content)
 	content = sanitizeUTF8(content)
 	if content == "" {
 		return nil
 	}
-	if len(content) <= maxLen {
+	if approxTokens(content) <= maxTokens {
 		return []string{content}
 	}
 
@@ -406,7 +628,6 @@ func splitIntoChunks(content string, maxLen int) []string {
 			return
 		}
 		chunk := strings.TrimSpace(buf.String())
-		chunk = sanitizeUTF8(chunk)
 		if chunk != "" {
 			chunks = append(chunks, chunk)
 		}
@@ -419,18 +640,16 @@ func splitIntoChunks(content string, maxLen int) []string {
 			continue
 		}
 
-		for len(line) > maxLen {
-			part := line[:maxLen]
-			line = line[maxLen:]
+		for approxTokens(line) > maxTokens {
+			part, rest := cutRunes(line, maxTokens*charsPerToken)
+			line = rest
 
-			if buf.Len() > 0 {
-				flush()
-			}
+			flush()
 			buf.WriteString(part)
 			flush()
 		}
 
-		if buf.Len()+len(line)+1 > maxLen {
+		if buf.Len() > 0 && approxTokens(buf.String())+approxTokens(line) > maxTokens {
 			flush()
 		}
 
@@ -442,66 +661,20 @@ func splitIntoChunks(content string, maxLen int) []string {
 	return chunks
 }
 
-func detectSectionType(chunk string) rag.SectionType {
-	s := strings.ToLower(chunk)
-
-	switch {
-	case strings.Contains(s, "3ds") || strings.Contains(s, "3-d secure"):
-		return rag.Section3DS
-	case strings.Contains(s, "authorization") || strings.Contains(s, "autorização"):
-		return rag.SectionAuth
-	case strings.Contains(s, "endpoint") ||
-		(strings.Contains(s, "http") &&
-			(strings.Contains(s, "post") || strings.Contains(s, "get") ||
-				strings.Contains(s, "put") || strings.Contains(s, "delete"))):
-		return rag.SectionEndpoint
-	case strings.Contains(s, "error code") || strings.Contains(s, "código de erro"):
-		return rag.SectionErrors
-	default:
-		return rag.SectionOverview
+// cutRunes corta s em até n runas, preferindo o último espaço antes do limite.
+func cutRunes(s string, n int) (string, string) {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s, ""
 	}
-}
-
-func detectTags(chunk string) []string {
-	s := strings.ToLower(chunk)
-	var tags []string
-
-	add := func(t string) {
-		for _, ex := range tags {
-			if ex == t {
-				return
-			}
+	cut := n
+	for i := n; i > n/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
 		}
-		tags = append(tags, t)
-	}
-
-	if strings.Contains(s, "3ds") || strings.Contains(s, "3-d secure") {
-		add("3ds")
-		add("auth")
-	}
-	if strings.Contains(s, "authorization") || strings.Contains(s, "autorização") {
-		add("authorization")
-	}
-	if strings.Contains(s, "capture") || strings.Contains(s, "captura") {
-		add("capture")
 	}
-	if strings.Contains(s, "refund") || strings.Contains(s, "estorno") {
-		add("refund")
-	}
-	if strings.Contains(s, "cancel") || strings.Contains(s, "void") {
-		add("cancel")
-	}
-	if strings.Contains(s, "webhook") || strings.Contains(s, "notificação") {
-		add("webhook")
-	}
-	if strings.Contains(s, "sandbox") {
-		add("sandbox")
-	}
-	if strings.Contains(s, "transaction") || strings.Contains(s, "transação") {
-		add("transaction")
-	}
-
-	return tags
+	return strings.TrimSpace(string(runes[:cut])), strings.TrimSpace(string(runes[cut:]))
 }
 
 func extractTextFromPDF(path string) (string, error) {
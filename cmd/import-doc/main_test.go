@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/llm/fake"
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+)
+
+func TestImportDocumentWithoutChunksRemovesOldOnes(t *testing.T) {
+	ctx := context.Background()
+	repo, err := rag.NewMemoryRepository("", rag.MetricCosine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := fake.New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imp := &importer{repo: repo, embed: newEmbedPool(client, 1, 10, 0, 0), provider: rag.ProviderRede}
+
+	if err := imp.importDocument(ctx, "docs/a.md", "", "v1", []piece{{Title: "a", Content: "estorno via POST /v1/refunds"}}); err != nil {
+		t.Fatal(err)
+	}
+	// a nova versão não gera nenhum chunk (ex: só imagens ou markup)
+	if err := imp.importDocument(ctx, "docs/a.md", "", "v2", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := repo.GetDocument(ctx, rag.ProviderRede, "docs/a.md")
+	if err != nil || doc == nil || doc.ContentHash != imp.contentHash("v2") {
+		t.Fatalf("document = %+v, %v", doc, err)
+	}
+	if found, _ := repo.SearchSimilarChunks(ctx, rag.ProviderRede, []float32{1, 0, 0, 0, 0, 0, 0, 0}, 10, rag.SearchFilters{}); len(found) != 0 {
+		t.Errorf("stale chunks still searchable: %+v", found)
+	}
+}
+
+func TestImportDocumentReprocessesOnChunkOptionsChange(t *testing.T) {
+	ctx := context.Background()
+	repo, err := rag.NewMemoryRepository("", rag.MetricCosine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := fake.New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imp := &importer{repo: repo, embed: newEmbedPool(client, 1, 10, 0, 0), provider: rag.ProviderRede, chunking: chunkOptions{maxTokens: 500, overlapTokens: 50}}
+
+	content := "# Estorno\n\nestorno via POST /v1/refunds"
+	if err := imp.importDocument(ctx, "docs/a.md", "", content, []piece{{Title: "a", Content: content}}); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := repo.GetDocument(ctx, rag.ProviderRede, "docs/a.md")
+
+	// mesmo texto e mesmas opções: pulado
+	if imp.contentHash(content) != first.ContentHash {
+		t.Fatal("same content and options must keep the hash")
+	}
+
+	// outras opções de chunking mudam o hash: o documento é reprocessado
+	imp.chunking.maxTokens = 200
+	if imp.contentHash(content) == first.ContentHash {
+		t.Fatal("chunk options must be part of the hash")
+	}
+	if err := imp.importDocument(ctx, "docs/a.md", "", content, []piece{{Title: "a", Content: content}}); err != nil {
+		t.Fatal(err)
+	}
+	second, _ := repo.GetDocument(ctx, rag.ProviderRede, "docs/a.md")
+	if second.ContentHash != imp.contentHash(content) {
+		t.Errorf("document was not reprocessed: %+v", second)
+	}
+}
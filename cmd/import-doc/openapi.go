@@ -0,0 +1,546 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+	"gopkg.in/yaml.v3"
+)
+
+// importFromOpenAPI importa uma spec OpenAPI 3 / Swagger 2 (JSON ou YAML, arquivo ou URL)
+// gerando um chunk por operação (método + path) com parâmetros, schemas e exemplos.
+func (imp *importer) importFromOpenAPI(ctx context.Context, specPath string) error {
+	log.Printf("📑 Importando spec OpenAPI %s para provider=%s", specPath, imp.provider)
+
+	data, err := readSource(specPath)
+	if err != nil {
+		return err
+	}
+
+	var root map[string]any
+	// YAML é superconjunto de JSON, então o mesmo parser serve para os dois formatos.
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("spec inválida %s: %w", specPath, err)
+	}
+
+	spec := &openAPISpec{root: normalizeYAML(root).(map[string]any)}
+	root = spec.root
+	if spec.str(root, "openapi") == "" && spec.str(root, "swagger") == "" {
+		return fmt.Errorf("%s não parece uma spec OpenAPI/Swagger (sem campo openapi/swagger)", specPath)
+	}
+
+	info := spec.obj(root, "info")
+	apiTitle := spec.str(info, "title")
+	if apiTitle == "" {
+		apiTitle = filenameToTitle(specPath)
+	}
+
+	// sem --api-version, usa a versão declarada na spec
+	if imp.apiVersion == "" {
+		imp.apiVersion = spec.str(info, "version")
+		defer func() { imp.apiVersion = "" }()
+	}
+
+	var pieces []piece
+	for _, op := range spec.operations() {
+		title := apiTitle + " > " + op.method + " " + op.path
+		if len(op.tags) > 0 {
+			title = apiTitle + " > " + op.tags[0] + " > " + op.method + " " + op.path
+		}
+
+		sec := section{blocks: spec.renderOperation(op)}
+		for _, p := range packSections(title, []section{sec}, imp.chunking) {
+			p.SectionType = rag.SectionEndpoint
+			p.Tags = slugTags(op.tags)
+			pieces = append(pieces, p)
+		}
+	}
+
+	if len(pieces) == 0 {
+		log.Printf("nenhuma operação encontrada em %s", specPath)
+		return nil
+	}
+
+	sourceURL := ""
+	if isURL(specPath) {
+		sourceURL = specPath
+	}
+
+	return imp.importDocument(ctx, specPath, sourceURL, string(data), pieces)
+}
+
+type openAPIOperation struct {
+	method string
+	path   string
+	tags   []string
+	op     map[string]any
+	params []any // parâmetros do path item + da operação
+}
+
+// openAPISpec embrulha o documento decodificado (map genérico) com helpers
+// de acesso e resolução de $ref locais (#/components/..., #/definitions/...).
+type openAPISpec struct {
+	root map[string]any
+}
+
+var openAPIMethods = []string{"get", "post", "put", "patch", "delete", "head", "options"}
+
+func (s *openAPISpec) operations() []openAPIOperation {
+	paths := s.obj(s.root, "paths")
+
+	var ops []openAPIOperation
+	for _, path := range sortedKeys(paths) {
+		item := s.deref(paths[path])
+		common := s.list(item, "parameters")
+
+		for _, m := range openAPIMethods {
+			op := s.deref(item[m])
+			if op == nil {
+				continue
+			}
+
+			var tags []string
+			for _, t := range s.list(op, "tags") {
+				if ts, ok := t.(string); ok {
+					tags = append(tags, ts)
+				}
+			}
+
+			ops = append(ops, openAPIOperation{
+				method: strings.ToUpper(m),
+				path:   path,
+				tags:   tags,
+				op:     op,
+				params: append(append([]any{}, common...), s.list(op, "parameters")...),
+			})
+		}
+	}
+	return ops
+}
+
+// renderOperation gera os blocos de texto de uma operação, em Markdown.
+func (s *openAPISpec) renderOperation(o openAPIOperation) []string {
+	var blocks []string
+
+	var head strings.Builder
+	head.WriteString(o.method + " " + o.path)
+	if base := s.baseURL(); base != "" {
+		head.WriteString("\nURL: " + o.method + " " + strings.TrimRight(base, "/") + o.path)
+	}
+	if v := s.str(o.op, "summary"); v != "" {
+		head.WriteString("\nResumo: " + v)
+	}
+	if v := s.str(o.op, "operationId"); v != "" {
+		head.WriteString("\nOperation ID: " + v)
+	}
+	if len(o.tags) > 0 {
+		head.WriteString("\nTags: " + strings.Join(o.tags, ", "))
+	}
+	if deprecated, _ := o.op["deprecated"].(bool); deprecated {
+		head.WriteString("\nDEPRECATED")
+	}
+	blocks = append(blocks, head.String())
+
+	if v := strings.TrimSpace(s.str(o.op, "description")); v != "" {
+		blocks = append(blocks, v)
+	}
+
+	// parâmetros (path/query/header/cookie); no Swagger 2 o body também vem aqui
+	var rows []string
+	var swaggerBody map[string]any
+	for _, raw := range o.params {
+		p := s.deref(raw)
+		if p == nil {
+			continue
+		}
+		in := s.str(p, "in")
+		if in == "body" {
+			swaggerBody = p
+			continue
+		}
+		typ := s.str(p, "type")
+		if schema := s.deref(p["schema"]); schema != nil {
+			typ = s.schemaType(schema)
+		}
+		required := "não"
+		if r, _ := p["required"].(bool); r {
+			required = "sim"
+		}
+		rows = append(rows, fmt.Sprintf("| %s | %s | %s | %s | %s |",
+			s.str(p, "name"), in, typ, required, oneLineCell(s.str(p, "description"))))
+	}
+	if len(rows) > 0 {
+		blocks = append(blocks, "Parâmetros:\n| nome | em | tipo | obrigatório | descrição |\n| --- | --- | --- | --- | --- |\n"+strings.Join(rows, "\n"))
+	}
+
+	// corpo da requisição
+	if body := s.deref(o.op["requestBody"]); body != nil {
+		content := s.obj(body, "content")
+		for _, mediaType := range sortedKeys(content) {
+			m := s.deref(content[mediaType])
+			blocks = append(blocks, s.renderPayload("Request body ("+mediaType+")", m["schema"], m)...)
+		}
+	} else if swaggerBody != nil {
+		blocks = append(blocks, s.renderPayload("Request body", swaggerBody["schema"], swaggerBody)...)
+	}
+
+	// respostas
+	responses := s.obj(o.op, "responses")
+	for _, code := range sortedKeys(responses) {
+		r := s.deref(responses[code])
+		label := "Response " + code
+		if d := s.str(r, "description"); d != "" {
+			label += " — " + oneLineCell(d)
+		}
+
+		content := s.obj(r, "content")
+		if len(content) == 0 {
+			// Swagger 2: schema/examples direto na resposta
+			if s.deref(r["schema"]) != nil || r["examples"] != nil {
+				blocks = append(blocks, s.renderPayload(label, r["schema"], r)...)
+			} else {
+				blocks = append(blocks, label)
+			}
+			continue
+		}
+		for _, mediaType := range sortedKeys(content) {
+			m := s.deref(content[mediaType])
+			blocks = append(blocks, s.renderPayload(label+" ("+mediaType+")", m["schema"], m)...)
+		}
+	}
+
+	return blocks
+}
+
+// renderPayload descreve um schema (ainda com $ref, para o controle de recursão) como
+// lista de campos e anexa os exemplos que existirem.
+func (s *openAPISpec) renderPayload(label string, rawSchema any, holder map[string]any) []string {
+	var blocks []string
+	schema, seen, _ := s.descend(rawSchema, nil)
+
+	var b strings.Builder
+	b.WriteString(label + ":")
+	if schema != nil {
+		b.WriteString("\n")
+		s.renderSchema(&b, schema, 0, seen)
+	}
+	blocks = append(blocks, strings.TrimRight(b.String(), "\n"))
+
+	var examples []any
+	if ex, ok := holder["example"]; ok {
+		examples = append(examples, ex)
+	}
+	named := s.obj(holder, "examples")
+	for _, name := range sortedKeys(named) {
+		ex := named[name]
+		// OpenAPI 3: {name: {value: ...}}; Swagger 2: {mediaType: exemplo}
+		if e := s.deref(ex); e != nil {
+			if v, ok := e["value"]; ok {
+				examples = append(examples, v)
+				continue
+			}
+		}
+		examples = append(examples, ex)
+	}
+	if len(examples) == 0 && schema != nil {
+		if ex, ok := schema["example"]; ok {
+			examples = append(examples, ex)
+		}
+	}
+	for _, ex := range examples {
+		js, err := json.MarshalIndent(ex, "", "  ")
+		if err != nil {
+			continue
+		}
+		blocks = append(blocks, "Exemplo:\n```json\n"+string(js)+"\n```")
+	}
+
+	return blocks
+}
+
+const maxSchemaDepth = 6
+
+// renderSchema escreve os campos de um schema como lista aninhada:
+// "- campo (tipo, obrigatório): descrição".
+func (s *openAPISpec) renderSchema(b *strings.Builder, schema map[string]any, depth int, seen []string) {
+	if schema == nil || depth > maxSchemaDepth {
+		return
+	}
+	indent := strings.Repeat("  ", depth)
+
+	// allOf: junta as propriedades de todos
+	for _, part := range s.list(schema, "allOf") {
+		if p, next, ok := s.descend(part, seen); ok {
+			s.renderSchema(b, p, depth, next)
+		}
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		for i, part := range s.list(schema, key) {
+			p := s.deref(part)
+			fmt.Fprintf(b, "%s- %s opção %d (%s)\n", indent, key, i+1, s.schemaType(p))
+			if sub, next, ok := s.descend(part, seen); ok {
+				s.renderSchema(b, sub, depth+1, next)
+			}
+		}
+	}
+
+	if s.str(schema, "type") == "array" && schema["items"] != nil {
+		if items, next, ok := s.descend(schema["items"], seen); ok {
+			s.renderSchema(b, items, depth, next)
+		}
+		return
+	}
+
+	required := make(map[string]bool)
+	for _, r := range s.list(schema, "required") {
+		if rs, ok := r.(string); ok {
+			required[rs] = true
+		}
+	}
+
+	props := s.obj(schema, "properties")
+	for _, name := range sortedKeys(props) {
+		raw := props[name]
+		p := s.deref(raw)
+
+		attrs := []string{s.schemaType(p)}
+		if required[name] {
+			attrs = append(attrs, "obrigatório")
+		}
+		if f := s.str(p, "format"); f != "" {
+			attrs = append(attrs, f)
+		}
+		line := fmt.Sprintf("%s- %s (%s)", indent, name, strings.Join(attrs, ", "))
+		if d := s.str(p, "description"); d != "" {
+			line += ": " + oneLineCell(d)
+		}
+		if enum := s.list(p, "enum"); len(enum) > 0 {
+			line += fmt.Sprintf(" [valores: %v]", enum)
+		}
+		b.WriteString(line + "\n")
+
+		if p, next, ok := s.descend(raw, seen); ok {
+			s.renderSchema(b, p, depth+1, next)
+		}
+	}
+}
+
+// descend resolve um sub-schema e acrescenta o $ref dele em seen; ok é false quando o ref
+// já está no caminho (schema recursivo), para não entrar em loop.
+func (s *openAPISpec) descend(v any, seen []string) (map[string]any, []string, bool) {
+	ref := refOf(v)
+	if ref == "" {
+		return s.deref(v), seen, true
+	}
+	if contains(seen, ref) {
+		return nil, seen, false
+	}
+	return s.deref(v), append(seen[:len(seen):len(seen)], ref), true
+}
+
+func (s *openAPISpec) schemaType(schema map[string]any) string {
+	return s.schemaTypeDepth(schema, 0)
+}
+
+// schemaTypeDepth limita o aninhamento de array<array<...>> (um array de si mesmo não termina).
+func (s *openAPISpec) schemaTypeDepth(schema map[string]any, depth int) string {
+	if schema == nil || depth > maxSchemaDepth {
+		return "any"
+	}
+	t := s.str(schema, "type")
+	if t == "array" {
+		return "array<" + s.schemaTypeDepth(s.deref(schema["items"]), depth+1) + ">"
+	}
+	if t == "" {
+		if len(s.obj(schema, "properties")) > 0 || len(s.list(schema, "allOf")) > 0 {
+			return "object"
+		}
+		return "any"
+	}
+	return t
+}
+
+func (s *openAPISpec) baseURL() string {
+	// OpenAPI 3
+	for _, srv := range s.list(s.root, "servers") {
+		if m, ok := srv.(map[string]any); ok {
+			if u := s.str(m, "url"); u != "" {
+				return u
+			}
+		}
+	}
+	// Swagger 2
+	host := s.str(s.root, "host")
+	if host == "" {
+		return ""
+	}
+	scheme := "https"
+	if schemes := s.list(s.root, "schemes"); len(schemes) > 0 {
+		if sc, ok := schemes[0].(string); ok {
+			scheme = sc
+		}
+	}
+	return scheme + "://" + host + s.str(s.root, "basePath")
+}
+
+// deref resolve um $ref local; devolve o próprio mapa se não houver ref.
+func (s *openAPISpec) deref(v any) map[string]any {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil
+	}
+	for i := 0; i < maxSchemaDepth; i++ {
+		ref := refOf(m)
+		if ref == "" || !strings.HasPrefix(ref, "#/") {
+			return m
+		}
+		var cur any = s.root
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+			obj, ok := cur.(map[string]any)
+			if !ok {
+				return nil
+			}
+			cur = obj[part]
+		}
+		next, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		m = next
+	}
+	return m
+}
+
+func refOf(v any) string {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return ""
+	}
+	ref, _ := m["$ref"].(string)
+	return ref
+}
+
+func (s *openAPISpec) obj(m map[string]any, key string) map[string]any {
+	if m == nil {
+		return nil
+	}
+	v, _ := m[key].(map[string]any)
+	return v
+}
+
+func (s *openAPISpec) list(m map[string]any, key string) []any {
+	if m == nil {
+		return nil
+	}
+	v, _ := m[key].([]any)
+	return v
+}
+
+func (s *openAPISpec) str(m map[string]any, key string) string {
+	if m == nil {
+		return ""
+	}
+	switch v := m[key].(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// -------- helpers --------
+
+func readSource(pathOrURL string) ([]byte, error) {
+	if !isURL(pathOrURL) {
+		data, err := os.ReadFile(pathOrURL)
+		if err != nil {
+			return nil, fmt.Errorf("erro lendo %s: %w", pathOrURL, err)
+		}
+		return data, nil
+	}
+
+	resp, err := http.Get(pathOrURL)
+	if err != nil {
+		return nil, fmt.Errorf("erro GET %s: %w", pathOrURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d em %s", resp.StatusCode, pathOrURL)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// normalizeYAML converte mapas com chaves não-string (ex: códigos de resposta
+// 200 sem aspas no YAML) para map[string]any, recursivamente.
+func normalizeYAML(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			t[k] = normalizeYAML(val)
+		}
+		return t
+	case map[any]any:
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			out[fmt.Sprint(k)] = normalizeYAML(val)
+		}
+		return out
+	case []any:
+		for i := range t {
+			t[i] = normalizeYAML(t[i])
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+func oneLineCell(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+// slugTags normaliza as tags da spec para o formato das tags do doc_chunk (minúsculas, com '-').
+func slugTags(tags []string) []string {
+	var out []string
+	for _, t := range tags {
+		t = strings.Join(strings.Fields(strings.ToLower(t)), "-")
+		if t != "" && !contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func contains(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
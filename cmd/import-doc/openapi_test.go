@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func loadSpec(t *testing.T, path string) *openAPISpec {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root map[string]any
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		t.Fatal(err)
+	}
+	return &openAPISpec{root: normalizeYAML(root).(map[string]any)}
+}
+
+func TestRenderOperationRecursiveSchemas(t *testing.T) {
+	spec := loadSpec(t, "testdata/recursive.yaml")
+
+	ops := spec.operations()
+	if len(ops) != 1 {
+		t.Fatalf("operations = %d, want 1", len(ops))
+	}
+	// array de si mesmo (items) e allOf que se referencia: sem o controle de $ref, estoura a pilha
+	content := strings.Join(spec.renderOperation(ops[0]), "\n\n")
+
+	for _, want := range []string{
+		"POST /lists",
+		"Resumo: Cria uma lista",
+		"Request body (application/json):",
+		"Response 200 — árvore criada (application/json):\n- children (array<object>)\n- name (string, obrigatório): nome do nó",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("missing %q in:\n%s", want, content)
+		}
+	}
+	if strings.Count(content, "- name ") != 1 {
+		t.Errorf("recursive Node rendered more than once:\n%s", content)
+	}
+}
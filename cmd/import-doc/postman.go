@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+)
+
+// importFromPostman importa uma collection Postman (v2.1): cada request vira um chunk
+// com o caminho de pastas no título, método, URL, headers, body e respostas salvas.
+func (imp *importer) importFromPostman(ctx context.Context, path string) error {
+	log.Printf("📮 Importando collection Postman %s para provider=%s", path, imp.provider)
+
+	data, err := readSource(path)
+	if err != nil {
+		return err
+	}
+
+	var col postmanCollection
+	if err := json.Unmarshal(data, &col); err != nil {
+		return fmt.Errorf("collection inválida %s: %w", path, err)
+	}
+	if !strings.Contains(col.Info.Schema, "v2.") {
+		log.Printf("aviso: schema %q não é v2.x; tentando importar mesmo assim", col.Info.Schema)
+	}
+
+	name := col.Info.Name
+	if name == "" {
+		name = filenameToTitle(path)
+	}
+
+	var pieces []piece
+	var walk func(items []postmanItem, folders []string)
+	walk = func(items []postmanItem, folders []string) {
+		for _, it := range items {
+			if it.Request == nil {
+				walk(it.Item, append(folders, it.Name))
+				continue
+			}
+
+			title := strings.Join(append(append([]string{name}, folders...), it.Name), " > ")
+			sec := section{blocks: renderPostmanItem(it, folders)}
+			for _, p := range packSections(title, []section{sec}, imp.chunking) {
+				p.SectionType = rag.SectionEndpoint
+				p.Tags = slugTags(folders)
+				pieces = append(pieces, p)
+			}
+		}
+	}
+	walk(col.Item, nil)
+
+	if len(pieces) == 0 {
+		log.Printf("nenhuma request encontrada em %s", path)
+		return nil
+	}
+
+	sourceURL := ""
+	if isURL(path) {
+		sourceURL = path
+	}
+
+	return imp.importDocument(ctx, path, sourceURL, string(data), pieces)
+}
+
+func renderPostmanItem(it postmanItem, folders []string) []string {
+	req := it.Request
+	var blocks []string
+
+	var head strings.Builder
+	head.WriteString(strings.ToUpper(req.Method) + " " + req.URL.Raw)
+	if len(folders) > 0 {
+		head.WriteString("\nPasta: " + strings.Join(folders, " > "))
+	}
+	head.WriteString("\nRequest: " + it.Name)
+	blocks = append(blocks, head.String())
+
+	for _, d := range []string{string(it.Description), string(req.Description)} {
+		if d = strings.TrimSpace(d); d != "" {
+			blocks = append(blocks, d)
+		}
+	}
+
+	if rows := kvRows(req.Header, true); len(rows) > 0 {
+		blocks = append(blocks, "Headers:\n| header | valor | descrição |\n| --- | --- | --- |\n"+strings.Join(rows, "\n"))
+	}
+	if rows := kvRows(req.URL.Query, false); len(rows) > 0 {
+		blocks = append(blocks, "Query params:\n| nome | valor | descrição |\n| --- | --- | --- |\n"+strings.Join(rows, "\n"))
+	}
+
+	if b := req.Body; b != nil {
+		switch b.Mode {
+		case "raw":
+			if strings.TrimSpace(b.Raw) != "" {
+				blocks = append(blocks, "Body (raw):\n"+codeBlock(b.Raw))
+			}
+		case "urlencoded":
+			if rows := kvRows(b.URLEncoded, false); len(rows) > 0 {
+				blocks = append(blocks, "Body (x-www-form-urlencoded):\n| campo | valor | descrição |\n| --- | --- | --- |\n"+strings.Join(rows, "\n"))
+			}
+		case "formdata":
+			if rows := kvRows(b.FormData, false); len(rows) > 0 {
+				blocks = append(blocks, "Body (form-data):\n| campo | valor | descrição |\n| --- | --- | --- |\n"+strings.Join(rows, "\n"))
+			}
+		case "graphql":
+			if len(b.GraphQL) > 0 {
+				blocks = append(blocks, "Body (graphql):\n"+codeBlock(string(b.GraphQL)))
+			}
+		}
+	}
+
+	for _, r := range it.Response {
+		label := "Resposta salva"
+		if r.Name != "" {
+			label += " \"" + r.Name + "\""
+		}
+		if r.Code != 0 {
+			label += fmt.Sprintf(" — %d %s", r.Code, r.Status)
+		}
+		if strings.TrimSpace(r.Body) == "" {
+			blocks = append(blocks, label)
+			continue
+		}
+		blocks = append(blocks, label+":\n"+codeBlock(r.Body))
+	}
+
+	return blocks
+}
+
+// kvRows gera linhas de tabela Markdown para headers/params, pulando os desabilitados.
+// Com maskSecrets, valores de headers de credencial são mascarados (exceto variáveis {{...}}).
+func kvRows(kvs []postmanKV, maskSecrets bool) []string {
+	var rows []string
+	for _, kv := range kvs {
+		if kv.Disabled || kv.Key == "" {
+			continue
+		}
+		value := kv.Value
+		if maskSecrets && isSecretHeader(kv.Key) && !strings.HasPrefix(strings.TrimSpace(value), "{{") {
+			value = "***"
+		}
+		rows = append(rows, fmt.Sprintf("| %s | %s | %s |", kv.Key, oneLineCell(value), oneLineCell(string(kv.Description))))
+	}
+	return rows
+}
+
+func isSecretHeader(key string) bool {
+	k := strings.ToLower(key)
+	return k == "authorization" || strings.Contains(k, "token") ||
+		strings.Contains(k, "api-key") || strings.Contains(k, "apikey") ||
+		strings.Contains(k, "secret") || strings.Contains(k, "password")
+}
+
+// codeBlock formata JSON válido com indentação; outros formatos vão como estão.
+func codeBlock(body string) string {
+	body = strings.TrimSpace(body)
+	var buf bytes.Buffer
+	if json.Valid([]byte(body)) && json.Indent(&buf, []byte(body), "", "  ") == nil {
+		return "```json\n" + buf.String() + "\n```"
+	}
+	return "```\n" + body + "\n```"
+}
+
+// -------- tipos da collection v2.1 --------
+// Só o que usamos; vários campos aceitam string ou objeto, por isso os UnmarshalJSON.
+
+type postmanCollection struct {
+	Info struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item []postmanItem `json:"item"`
+}
+
+type postmanItem struct {
+	Name        string             `json:"name"`
+	Description postmanDescription `json:"description"`
+	Item        []postmanItem      `json:"item"`
+	Request     *postmanRequest    `json:"request"`
+	Response    []postmanResponse  `json:"response"`
+}
+
+type postmanRequest struct {
+	Method      string             `json:"method"`
+	Header      []postmanKV        `json:"header"`
+	Body        *postmanBody       `json:"body"`
+	URL         postmanURL         `json:"url"`
+	Description postmanDescription `json:"description"`
+}
+
+func (r *postmanRequest) UnmarshalJSON(data []byte) error {
+	// request pode ser só a URL em string
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		r.Method = "GET"
+		r.URL.Raw = raw
+		return nil
+	}
+	type plain postmanRequest
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*r = postmanRequest(p)
+	if r.Method == "" {
+		r.Method = "GET"
+	}
+	return nil
+}
+
+type postmanURL struct {
+	Raw   string      `json:"raw"`
+	Query []postmanKV `json:"query"`
+}
+
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		u.Raw = raw
+		return nil
+	}
+	type plain postmanURL
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*u = postmanURL(p)
+	return nil
+}
+
+type postmanBody struct {
+	Mode       string          `json:"mode"`
+	Raw        string          `json:"raw"`
+	URLEncoded []postmanKV     `json:"urlencoded"`
+	FormData   []postmanKV     `json:"formdata"`
+	GraphQL    json.RawMessage `json:"graphql"`
+}
+
+type postmanKV struct {
+	Key         string             `json:"key"`
+	Value       string             `json:"value"`
+	Description postmanDescription `json:"description"`
+	Disabled    bool               `json:"disabled"`
+}
+
+type postmanResponse struct {
+	Name   string `json:"name"`
+	Code   int    `json:"code"`
+	Status string `json:"status"`
+	Body   string `json:"body"`
+}
+
+// postmanDescription aceita tanto "texto" quanto {"content": "texto", "type": "text/markdown"}.
+type postmanDescription string
+
+func (d *postmanDescription) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*d = postmanDescription(raw)
+		return nil
+	}
+	var obj struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil // formato desconhecido: ignora a descrição em vez de falhar a collection
+	}
+	*d = postmanDescription(obj.Content)
+	return nil
+}
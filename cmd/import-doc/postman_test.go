@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/llm/fake"
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+)
+
+func TestImportFromPostman(t *testing.T) {
+	ctx := context.Background()
+	repo, err := rag.NewMemoryRepository("", rag.MetricL2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := fake.New(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imp := &importer{repo: repo, embed: newEmbedPool(client, 1, 10, 0, 0), provider: rag.ProviderRede, chunking: chunkOptions{maxTokens: 500}}
+
+	if err := imp.importFromPostman(ctx, "testdata/collection.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	chunks, err := repo.SearchSimilarChunks(ctx, rag.ProviderRede, []float32{1, 0, 0, 0, 0, 0, 0, 0}, 50, rag.SearchFilters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byTitle := make(map[string]rag.DocChunk)
+	for _, c := range chunks {
+		byTitle[c.Title] = c
+	}
+	if len(byTitle) != 5 {
+		t.Fatalf("titles = %v, want one chunk per request", keys(byTitle))
+	}
+
+	// pastas aninhadas: caminho no título, na linha "Pasta:" e nas tags
+	refund, ok := byTitle["e-Rede > Transações > Estornos > Estornar transação"]
+	if !ok {
+		t.Fatalf("refund chunk missing: %v", keys(byTitle))
+	}
+	if refund.SectionType != rag.SectionEndpoint || !contains(refund.Tags, "transações") || !contains(refund.Tags, "estornos") {
+		t.Errorf("refund section/tags = %s %v", refund.SectionType, refund.Tags)
+	}
+	for _, want := range []string{
+		"POST {{baseUrl}}/v1/transactions/:tid/refunds?reference=abc",
+		"Pasta: Transações > Estornos",
+		"Estorna total ou parcialmente.",             // description em objeto
+		"| Authorization | *** |",                    // credencial mascarada
+		"| X-Api-Token | {{token}} |",                // variável fica visível
+		"| reference | abc | referência do pedido |", // query param
+		"Body (raw):\n```json\n{\n  \"amount\": 100\n}\n```",
+		"Resposta salva \"Sucesso\" — 201 Created:",
+	} {
+		if !strings.Contains(refund.Content, want) {
+			t.Errorf("refund chunk missing %q:\n%s", want, refund.Content)
+		}
+	}
+	if strings.Contains(refund.Content, "X-Debug") || strings.Contains(refund.Content, "debug") {
+		t.Errorf("disabled header/param rendered:\n%s", refund.Content)
+	}
+
+	// request em string vira GET
+	if c := byTitle["e-Rede > Transações > Consultar transação"]; !strings.HasPrefix(c.Content, "GET {{baseUrl}}/v1/transactions/:tid") {
+		t.Errorf("string request = %q", c.Content)
+	}
+
+	bodies := map[string]string{
+		"e-Rede > Token OAuth":         "Body (x-www-form-urlencoded):\n| campo | valor | descrição |\n| --- | --- | --- |\n| grant_type | client_credentials |  |",
+		"e-Rede > Upload de documento": "Body (form-data):\n| campo | valor | descrição |\n| --- | --- | --- |\n| file | doc.pdf | arquivo |",
+		"e-Rede > Consulta GraphQL":    "Body (graphql):\n```json\n{\n  \"query\": \"{ transaction { tid } }\"\n}\n```",
+	}
+	for title, want := range bodies {
+		if c := byTitle[title]; !strings.Contains(c.Content, want) {
+			t.Errorf("%s missing %q:\n%s", title, want, c.Content)
+		}
+	}
+
+	// {{baseUrl}} sai do path no catálogo de endpoints
+	eps, err := repo.ListEndpoints(ctx, rag.ProviderRede)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, e := range eps {
+		paths = append(paths, e.Method+" "+e.Path)
+	}
+	sort.Strings(paths)
+	want := []string{"GET /v1/transactions/:tid", "POST /graphql", "POST /oauth/token", "POST /v1/transactions/:tid/refunds", "PUT /v1/documents"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("endpoints = %v, want %v", paths, want)
+	}
+}
+
+func keys(m map[string]rag.DocChunk) []string {
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
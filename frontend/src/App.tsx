@@ -1,7 +1,7 @@
-import React, { useState } from "react";
-import type { AskRequest, AskResponse, Message, AskSource } from "./types";
-import { stripSourcesFromContent } from "./utils";
-import ReactMarkdown from "react-markdown";
+import React, { useEffect, useState } from "react";
+import type { AskRequest, AskResponse, Message, AskSource, ProviderInfo } from "./types";
+import { CITATION_HREF_PREFIX, linkCitations } from "./utils";
+import ReactMarkdown, { type Components } from "react-markdown";
 import remarkGfm from "remark-gfm";
 
 const RAW_API_URL = (import.meta as any).env?.VITE_API_URL || "http://localhost:8080";
@@ -10,7 +10,11 @@ const API_URL = RAW_API_URL.replace(/\/+$/, "");
 export const App: React.FC = () => {
   const [lang, setLang] = useState<"auto" | "pt" | "en" | "es">("auto");
 
-  const [provider, setProvider] = useState<"rede" | "entrepay">("rede");
+  const [providers, setProviders] = useState<ProviderInfo[]>([
+    { slug: "rede", displayName: "e-Rede", aliases: [], docUrls: [], enabled: true },
+    { slug: "entrepay", displayName: "Entrepay", aliases: [], docUrls: [], enabled: true }
+  ]);
+  const [provider, setProvider] = useState("rede");
   const [question, setQuestion] = useState("");
   const [topK, setTopK] = useState(5);
   const [messages, setMessages] = useState<Message[]>([]);
@@ -19,6 +23,18 @@ export const App: React.FC = () => {
 
   const [showTopKHelp, setShowTopKHelp] = useState(false);
 
+  // gateways cadastrados na API; se falhar, ficam os padrões acima
+  useEffect(() => {
+    fetch(`${API_URL}/providers`)
+      .then((res) => (res.ok ? res.json() : Promise.reject(res.status)))
+      .then((data: ProviderInfo[]) => {
+        if (data.length === 0) return;
+        setProviders(data);
+        setProvider((cur) => (data.some((p) => p.slug === cur) ? cur : data[0].slug));
+      })
+      .catch(() => {});
+  }, []);
+
   const handleAsk = async () => {
     const trimmed = question.trim();
     if (!trimmed || loading) return;
@@ -38,7 +54,7 @@ export const App: React.FC = () => {
     try {
       const payload: AskRequest = {
         question: trimmed,
-        provider,
+        ...(provider === "all" ? { providers: ["all"] } : { provider }),
         topK,
         lang
       };
@@ -63,7 +79,8 @@ export const App: React.FC = () => {
         id: crypto.randomUUID(),
         role: "assistant",
         content: data.answer || "No answer returned.",
-        sources: data.sources || []
+        sources: data.sources || [],
+        citations: data.citations || []
       };
 
       setMessages((prev) => [...prev, assistantMessage]);
@@ -122,26 +139,30 @@ export const App: React.FC = () => {
               Provider
             </span>
             <div className="flex items-center gap-2 bg-slate-900/80 border border-slate-700 rounded-full px-1 py-1 text-xs">
-              <button
-                className={`px-3 py-1 rounded-full ${provider === "rede"
-                    ? "bg-emerald-500 text-slate-950 font-semibold"
-                    : "text-slate-400 hover:text-slate-100"
-                  }`}
-                onClick={() => setProvider("rede")}
-              >
-                e-Rede
-              </button>
-              <button
-                className={`px-3 py-1 rounded-full ${provider === "entrepay"
-                    ? "bg-emerald-500 text-slate-950 font-semibold"
-                    : "text-slate-500 cursor-not-allowed"
-                  }`}
-                onClick={() => {
-                  setProvider("entrepay");
-                }}
-              >
-                Entrepay
-              </button>
+              {providers.map((p) => (
+                <button
+                  key={p.slug}
+                  className={`px-3 py-1 rounded-full ${provider === p.slug
+                      ? "bg-emerald-500 text-slate-950 font-semibold"
+                      : "text-slate-400 hover:text-slate-100"
+                    }`}
+                  onClick={() => setProvider(p.slug)}
+                >
+                  {p.displayName}
+                </button>
+              ))}
+              {providers.length > 1 && (
+                <button
+                  className={`px-3 py-1 rounded-full ${provider === "all"
+                      ? "bg-emerald-500 text-slate-950 font-semibold"
+                      : "text-slate-400 hover:text-slate-100"
+                    }`}
+                  onClick={() => setProvider("all")}
+                  title="Compara a resposta entre todos os gateways"
+                >
+                  Comparar
+                </button>
+              )}
             </div>
           </div>
         </div>
@@ -262,7 +283,8 @@ export const App: React.FC = () => {
             </div>
             <p className="mt-1 text-[10px] text-slate-500">
               Enter para enviar, Shift+Enter para quebrar linha. As respostas
-              usam apenas a documentação indexada para <b>{provider}</b>.
+              usam apenas a documentação indexada para{" "}
+              <b>{provider === "all" ? "todos os gateways" : provider}</b>.
             </p>
           </div>
         </div>
@@ -273,7 +295,35 @@ export const App: React.FC = () => {
 
 const MessageBubble: React.FC<{ msg: Message }> = ({ msg }) => {
   const isUser = msg.role === "user";
-  const content = isUser ? msg.content : stripSourcesFromContent(msg.content);
+  const content = isUser ? msg.content : linkCitations(msg.content, msg.citations);
+  const sourceAnchor = (ref: number) => `src-${msg.id}-${ref}`;
+
+  // [n] da resposta: leva para a fonte n (link da documentação ou o chip abaixo)
+  const components: Components = {
+    a: ({ node: _node, href, children, ...props }) => {
+      if (!href?.startsWith(CITATION_HREF_PREFIX)) {
+        return (
+          <a href={href} target="_blank" rel="noreferrer" {...props}>
+            {children}
+          </a>
+        );
+      }
+      const ref = Number(href.slice(CITATION_HREF_PREFIX.length));
+      const source = msg.sources?.find((s) => s.ref === ref);
+      const claim = msg.citations?.find((c) => c.refs.includes(ref));
+      return (
+        <a
+          href={source?.sourceUrl || `#${sourceAnchor(ref)}`}
+          target={source?.sourceUrl ? "_blank" : "_self"}
+          rel="noreferrer"
+          title={source ? `${source.title || `Chunk ${source.chunkId}`}${claim ? ` — ${claim.text}` : ""}` : undefined}
+          className="no-underline text-emerald-300 hover:text-emerald-200 text-[0.8em] align-super"
+        >
+          {children}
+        </a>
+      );
+    }
+  };
 
   return (
     <div
@@ -290,7 +340,7 @@ const MessageBubble: React.FC<{ msg: Message }> = ({ msg }) => {
           <div className="whitespace-pre-wrap">{content}</div>
         ) : (
           <div className="prose prose-invert prose-sm max-w-none">
-            <ReactMarkdown remarkPlugins={[remarkGfm]}>
+            <ReactMarkdown remarkPlugins={[remarkGfm]} components={components}>
               {content}
             </ReactMarkdown>
           </div>
@@ -306,6 +356,7 @@ const MessageBubble: React.FC<{ msg: Message }> = ({ msg }) => {
               {msg.sources.map((s) => (
                 <a
                   key={s.chunkId + s.title}
+                  id={sourceAnchor(s.ref)}
                   href={s.sourceUrl || "#"}
                   target={s.sourceUrl ? "_blank" : "_self"}
                   rel="noreferrer"
@@ -314,7 +365,7 @@ const MessageBubble: React.FC<{ msg: Message }> = ({ msg }) => {
                       : "border-slate-700 text-slate-400"
                     }`}
                 >
-                  {s.title || `Chunk ${s.chunkId}`}
+                  [{s.ref}] {s.title || `Chunk ${s.chunkId}`}
                 </a>
               ))}
             </div>
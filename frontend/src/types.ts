@@ -1,21 +1,114 @@
 export interface AskRequest {
   question: string;
-  provider: string;
+  provider?: string;
+  providers?: string[]; // modo comparação; ["all"] compara todos os gateways
   topK: number;
   lang?: string;
+  filters?: SearchFilters;
+}
+
+export interface AskIntent {
+  name: string; // error_code, 3ds, refund, cancel, capture, authorization, webhook, sandbox, general
+  sectionTypes?: string[];
+  tags?: string[];
+  code?: string;
+  applied?: "boost" | "filter";
+}
+
+export interface SearchFilters {
+  sectionTypes?: ("overview" | "auth" | "endpoint" | "3ds" | "errors")[];
+  tagsAll?: string[];
+  tagsAny?: string[];
+  apiVersion?: string;
+}
+
+export interface ProviderInfo {
+  slug: string;
+  displayName: string;
+  aliases: string[];
+  ambiguousAliases?: string[];
+  docUrls: string[];
+  defaultApiVersion?: string;
+  enabled: boolean;
 }
 
 export interface AskSource {
+  ref: number; // número usado nas marcações [n] da resposta
   chunkId: number;
   title: string;
   provider: string;
   sourceUrl?: string;
 }
 
+export interface AskCitation {
+  text: string;
+  start: number;
+  end: number;
+  refs: number[];
+  chunkIds: number[];
+}
+
+export interface AskWarning {
+  kind: "path" | "url" | "field" | "code";
+  value: string;
+  message: string;
+}
+
 export interface AskResponse {
   answer: string;
   provider: string;
   sources: AskSource[];
+  citations: AskCitation[];
+  warnings?: AskWarning[];
+  intent?: AskIntent;
+  providers?: string[];
+  sourcesByProvider?: Record<string, AskSource[]>;
+  providerDetection?: ProviderDetection;
+  endpoints?: Endpoint[]; // catálogo, quando a pergunta procura um endpoint
+  errorCode?: ErrorCode; // resposta veio da tabela de códigos de erro (sem LLM)
+}
+
+export interface ErrorCode {
+  id: number;
+  provider: string;
+  code: string;
+  message: string;
+  category?: "fraud" | "funds" | "card" | "auth" | "system" | "validation" | "issuer";
+  retryable?: boolean;
+  chunkId: number;
+  title: string;
+  sourceUrl: string;
+  apiVersion?: string;
+}
+
+export interface EndpointParam {
+  name: string;
+  in?: string;
+  type?: string;
+  required?: boolean;
+  description?: string;
+}
+
+export interface Endpoint {
+  id: number;
+  provider: string;
+  method: string;
+  path: string;
+  description?: string;
+  parameters: EndpointParam[];
+  chunkId: number;
+  title: string;
+  sourceUrl: string;
+  apiVersion?: string;
+  score?: number;
+}
+
+export interface ProviderDetection {
+  providers?: string[];
+  method: "explicit" | "keyword" | "conversation" | "vector";
+  confidence: number;
+  candidates?: { provider: string; score: number }[];
+  ambiguous?: boolean; // a resposta pede para o usuário indicar o gateway
 }
 
 export interface Message {
@@ -23,4 +116,5 @@ export interface Message {
   role: "user" | "assistant";
   content: string;
   sources?: AskSource[];
+  citations?: AskCitation[];
 }
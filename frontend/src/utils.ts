@@ -1,11 +1,33 @@
-export function stripSourcesFromContent(content: string): string {
-  const lower = content.toLowerCase();
-  const marker = "\nsources";
-  const idx = lower.lastIndexOf(marker);
+import type { AskCitation } from "./types";
 
-  if (idx === -1) {
-    return content.trim();
+// Mesmo critério do backend (internal/rag/citations.go): [1, 2] dentro de código é array, não citação.
+const CODE_RE = /```[\s\S]*?```|`[^`\n]*`/g;
+const CITATION_RE = /\[(\d+(?:\s*,\s*\d+)*)\]/g;
+
+export const CITATION_HREF_PREFIX = "#cite-";
+
+// Troca as marcações [n] da resposta por links markdown para a fonte n, só para os
+// números que aparecem em response.citations; o resto do texto fica como veio da API.
+export function linkCitations(content: string, citations: AskCitation[] = []): string {
+  const refs = new Set(citations.flatMap((c) => c.refs));
+  if (refs.size === 0) {
+    return content;
+  }
+
+  const code: [number, number][] = [];
+  for (const m of content.matchAll(CODE_RE)) {
+    code.push([m.index!, m.index! + m[0].length]);
   }
+  const inCode = (pos: number) => code.some(([s, e]) => pos >= s && pos < e);
 
-  return content.slice(0, idx).trimEnd();
+  return content.replace(CITATION_RE, (marker: string, nums: string, pos: number) => {
+    if (inCode(pos)) {
+      return marker;
+    }
+    return nums
+      .split(",")
+      .map((n) => Number(n.trim()))
+      .map((n) => (refs.has(n) ? `[\\[${n}\\]](${CITATION_HREF_PREFIX}${n})` : `[${n}]`))
+      .join("");
+  });
 }
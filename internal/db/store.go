@@ -0,0 +1,28 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/config"
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+)
+
+// NewStore abre o armazenamento escolhido por STORAGE (postgres | memory).
+// close libera o pool do Postgres (no-op em memória).
+func NewStore(cfg *config.Config) (store rag.Store, close func(), err error) {
+	switch cfg.Storage {
+	case "", "postgres":
+		pool := NewPool(cfg.DatabaseURL)
+		return rag.NewPgRepository(pool), pool.Close, nil
+
+	case "memory":
+		repo, err := rag.NewMemoryRepository(cfg.MemoryStorePath, rag.DistanceMetric(cfg.MemoryMetric))
+		if err != nil {
+			return nil, nil, err
+		}
+		return repo, func() {}, nil
+
+	default:
+		return nil, nil, fmt.Errorf("invalid STORAGE %q (use postgres ou memory)", cfg.Storage)
+	}
+}
@@ -0,0 +1,351 @@
+package http_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	apphttp "github.com/josinaldojr/payment-gateway-rag/internal/http"
+	"github.com/josinaldojr/payment-gateway-rag/internal/llm/fake"
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+)
+
+// Teste ponta a ponta offline: backend fake + MemoryRepository, passando pelo router HTTP.
+
+var docs = []rag.DocChunk{
+	{Provider: rag.ProviderRede, SectionType: rag.SectionAuth, Title: "Rede > Autenticação", Content: "A autenticação usa OAuth 2.0 com client_id e client_secret para gerar o access_token."},
+	{Provider: rag.ProviderRede, SectionType: rag.SectionEndpoint, Title: "Rede > Estorno", Content: "Para estornar uma transação envie POST /v1/transactions/{tid}/refunds com o amount."},
+	{Provider: rag.ProviderEntrepay, SectionType: rag.SectionEndpoint, Title: "Entrepay > Estorno", Content: "O cancelamento de uma cobrança Entrepay é feito via DELETE /charges/{id}."},
+	{Provider: rag.ProviderRede, SectionType: rag.SectionErrors, Title: "Rede > Códigos de retorno", Content: "| Código | Mensagem |\n| --- | --- |\n| 58 | Transação não permitida para o cartão |\n| 101 | Erro interno, tente novamente |"},
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	emb, err := fake.New(768)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := rag.NewMemoryRepository("", rag.MetricCosine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range docs {
+		vec, err := emb.Embed(context.Background(), docs[i].Title+"\n"+docs[i].Content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs[i].Endpoints = rag.ExtractEndpoints(docs[i].Title, docs[i].Content)
+		if docs[i].SectionType == rag.SectionErrors {
+			docs[i].ErrorCodes = rag.ExtractErrorCodes(docs[i].Content)
+		}
+		if _, err := repo.InsertChunk(context.Background(), &docs[i], vec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// similaridade de embeddings por hashing é baixa em termos absolutos: sem corte
+	svc := rag.NewService(repo, emb, emb, rag.WithMinSimilarity(0), rag.WithProviders(repo), rag.WithEndpoints(repo), rag.WithErrorCodes(repo))
+	srv := httptest.NewServer(apphttp.NewRouter(apphttp.NewHandler(svc)))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAskOffline(t *testing.T) {
+	srv := newTestServer(t)
+
+	body := `{"question": "como estornar uma transação na rede?", "topK": 1}`
+	resp, err := http.Post(srv.URL+"/ask", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+
+	var out rag.AskResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Provider != rag.ProviderRede {
+		t.Errorf("provider = %q, want rede", out.Provider)
+	}
+	if len(out.Sources) != 1 || out.Sources[0].Title != "Rede > Estorno" {
+		t.Fatalf("sources = %+v, want only Rede > Estorno", out.Sources)
+	}
+	if !strings.Contains(out.Answer, "/refunds") {
+		t.Errorf("answer does not quote the chunk: %q", out.Answer)
+	}
+	if len(out.Citations) != 1 || out.Citations[0].ChunkIDs[0] != out.Sources[0].ChunkID {
+		t.Errorf("citations = %+v, want one pointing to the source", out.Citations)
+	}
+}
+
+func TestAskStreamOffline(t *testing.T) {
+	srv := newTestServer(t)
+
+	body := `{"question": "como funciona a autenticação da rede?", "topK": 2}`
+	resp, err := http.Post(srv.URL+"/ask/stream", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content-type = %q", ct)
+	}
+
+	var events []string
+	var answer strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if ev, ok := strings.CutPrefix(line, "event: "); ok {
+			events = append(events, ev)
+			continue
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok && events[len(events)-1] == "token" {
+			var ev rag.StreamEvent
+			if err := json.Unmarshal([]byte(data), &ev); err != nil {
+				t.Fatal(err)
+			}
+			answer.WriteString(ev.Token)
+		}
+	}
+
+	if len(events) < 3 || events[0] != "sources" || events[len(events)-1] != "done" {
+		t.Fatalf("events = %v, want sources, token..., done", events)
+	}
+	if !strings.Contains(answer.String(), "OAuth") {
+		t.Errorf("streamed answer does not quote the chunk: %q", answer.String())
+	}
+}
+
+func TestAskUnknownProvider(t *testing.T) {
+	srv := newTestServer(t)
+
+	// nenhum apelido e nenhum trecho parecido: não há como votar
+	resp, err := http.Post(srv.URL+"/ask", "application/json", bytes.NewBufferString(`{"question": "qual a cor do céu?"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestSearchOffline(t *testing.T) {
+	srv := newTestServer(t)
+
+	check := func(resp *http.Response, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d", resp.StatusCode)
+		}
+
+		var out rag.SearchResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		if len(out.Results) != 1 || out.Results[0].Title != "Rede > Estorno" {
+			t.Fatalf("results = %+v", out.Results)
+		}
+		if !strings.Contains(out.Results[0].Snippet, "<mark>estornar</mark>") {
+			t.Errorf("snippet = %q", out.Results[0].Snippet)
+		}
+		if out.Results[0].Similarity == 0 {
+			t.Errorf("similarity not returned")
+		}
+	}
+
+	check(http.Get(srv.URL + "/search?q=estornar+transação&provider=rede&topK=1"))
+	check(http.Post(srv.URL+"/search", "application/json", strings.NewReader(`{"query": "estornar transação", "provider": "rede", "topK": 1}`)))
+}
+
+func TestProvidersOffline(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/providers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var providers []rag.ProviderInfo
+	if err := json.NewDecoder(resp.Body).Decode(&providers); err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 2 || providers[0].Slug != rag.ProviderEntrepay || providers[1].Slug != rag.ProviderRede {
+		t.Fatalf("providers = %+v", providers)
+	}
+
+	// provider fora do cadastro é rejeitado
+	resp, err = http.Post(srv.URL+"/ask", "application/json", strings.NewReader(`{"question": "estorno", "provider": "cielo"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestEndpointsOffline(t *testing.T) {
+	srv := newTestServer(t)
+
+	get := func(path string, want int) []rag.Endpoint {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("%s: status = %d, want %d", path, resp.StatusCode, want)
+		}
+		var out []rag.Endpoint
+		if want == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return out
+	}
+
+	eps := get("/providers/rede/endpoints", http.StatusOK)
+	if len(eps) != 1 || eps[0].Method != "POST" || eps[0].Path != "/v1/transactions/{tid}/refunds" || eps[0].Title != "Rede > Estorno" {
+		t.Fatalf("endpoints = %+v", eps)
+	}
+	if eps := get("/providers/entrepay/endpoints?method=post", http.StatusOK); len(eps) != 0 {
+		t.Fatalf("entrepay POST = %+v", eps)
+	}
+	get("/providers/cielo/endpoints", http.StatusNotFound)
+
+	// pergunta sobre endpoint: o catálogo responde junto, citando o chunk
+	resp, err := http.Post(srv.URL+"/ask", "application/json", strings.NewReader(`{"question": "qual o endpoint de estorno na rede?"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out rag.AskResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Endpoints) != 1 || out.Endpoints[0].ChunkID != eps[0].ChunkID {
+		t.Fatalf("ask endpoints = %+v", out.Endpoints)
+	}
+}
+
+func TestAskDetectsProviderByVector(t *testing.T) {
+	srv := newTestServer(t)
+
+	ask := func(question string) rag.AskResponse {
+		t.Helper()
+		resp, err := http.Post(srv.URL+"/ask", "application/json", strings.NewReader(`{"question": "`+question+`"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%q: status = %d", question, resp.StatusCode)
+		}
+		var out rag.AskResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	// sem gateway na pergunta: os trechos mais próximos são todos da rede
+	out := ask("como estornar?")
+	if d := out.ProviderDetection; out.Provider != rag.ProviderRede || d == nil || d.Method != rag.DetectVector || d.Confidence < 0.6 {
+		t.Fatalf("provider = %q, detection = %+v", out.Provider, out.ProviderDetection)
+	}
+
+	// "rede" aqui é a palavra comum; a busca puxa para a entrepay e a votação empata
+	out = ask("erro de rede ao cancelar cobrança via DELETE /charges")
+	if d := out.ProviderDetection; d == nil || !d.Ambiguous || len(d.Candidates) != 2 || len(out.Sources) != 0 {
+		t.Fatalf("detection = %+v, sources = %d", out.ProviderDetection, len(out.Sources))
+	}
+	if !strings.Contains(out.Answer, "provider") {
+		t.Errorf("answer does not ask for the gateway: %q", out.Answer)
+	}
+
+	// o /search não pede esclarecimento: busca nos candidatos e devolve a detecção
+	resp, err := http.Get(srv.URL + "/search?q=" + url.QueryEscape("erro de rede ao cancelar cobrança via DELETE /charges"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("search status = %d", resp.StatusCode)
+	}
+	var search rag.SearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&search); err != nil {
+		t.Fatal(err)
+	}
+	if d := search.ProviderDetection; d == nil || !d.Ambiguous || len(search.Providers) != 2 || len(search.Results) == 0 {
+		t.Fatalf("search detection = %+v, providers = %v, results = %d", search.ProviderDetection, search.Providers, len(search.Results))
+	}
+}
+
+func TestErrorCodesOffline(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/providers/e-rede/errors/101")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	var ec rag.ErrorCode
+	if err := json.NewDecoder(resp.Body).Decode(&ec); err != nil {
+		t.Fatal(err)
+	}
+	if ec.Provider != rag.ProviderRede || ec.Category != "system" || ec.Retryable == nil || !*ec.Retryable || ec.Title != "Rede > Códigos de retorno" {
+		t.Fatalf("error code = %+v", ec)
+	}
+
+	for path, want := range map[string]int{
+		"/providers/rede/errors/99":      http.StatusNotFound,
+		"/providers/entrepay/errors/101": http.StatusNotFound,
+		"/providers/cielo/errors/58":     http.StatusNotFound,
+	} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s: status = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+
+	// no /ask o código cadastrado é respondido pela tabela
+	resp, err = http.Post(srv.URL+"/ask", "application/json", strings.NewReader(`{"question": "o que é o código 58 na e-Rede?", "lang": "pt"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out rag.AskResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ErrorCode == nil || out.ErrorCode.Code != "58" || !strings.HasPrefix(out.Answer, "Código 58 (e-Rede): Transação não permitida") {
+		t.Fatalf("answer = %q, errorCode = %+v", out.Answer, out.ErrorCode)
+	}
+}
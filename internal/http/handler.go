@@ -3,18 +3,43 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
 )
 
+// defaultAskTimeout vale para /ask e /ask/stream sem WithAskTimeout.
+const defaultAskTimeout = 2 * time.Minute
+
 type Handler struct {
 	ragService *rag.Service
+	askTimeout time.Duration
+}
+
+// HandlerOption configura o Handler.
+type HandlerOption func(*Handler)
+
+// WithAskTimeout define o limite de cada /ask e /ask/stream (ASK_TIMEOUT).
+func WithAskTimeout(d time.Duration) HandlerOption {
+	return func(h *Handler) {
+		if d > 0 {
+			h.askTimeout = d
+		}
+	}
 }
 
-func NewHandler(ragService *rag.Service) *Handler {
-	return &Handler{ragService: ragService}
+func NewHandler(ragService *rag.Service, opts ...HandlerOption) *Handler {
+	h := &Handler{ragService: ragService, askTimeout: defaultAskTimeout}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
@@ -29,7 +54,8 @@ func (h *Handler) Ask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	// condensação, reranker por LLM e retry de grounding somam chamadas ao modelo
+	ctx, cancel := context.WithTimeout(r.Context(), h.askTimeout)
 	defer cancel()
 	
 	if req.Lang == "" {
@@ -45,3 +71,185 @@ func (h *Handler) Ask(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(resp)
 }
+
+// Providers lista os gateways habilitados (tabela provider).
+func (h *Handler) Providers(w http.ResponseWriter, r *http.Request) {
+	providers, err := h.ragService.Providers(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if providers == nil {
+		providers = []rag.ProviderInfo{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(providers)
+}
+
+// Endpoints lista o catálogo de endpoints de um provider (slug ou apelido).
+// Filtros opcionais: ?method=POST e ?q=estorno (só os que casam, do mais relevante).
+func (h *Handler) Endpoints(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	provider := rag.Provider(mux.Vars(r)["provider"])
+
+	endpoints, err := h.ragService.Endpoints(r.Context(), provider, q.Get("q"), q.Get("method"))
+	if err != nil {
+		http.Error(w, err.Error(), providerStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(endpoints)
+}
+
+// ErrorCode consulta um código de erro/retorno do provider (404 se não está na tabela).
+func (h *Handler) ErrorCode(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	ec, err := h.ragService.ErrorCode(r.Context(), rag.Provider(vars["provider"]), vars["code"])
+	if err != nil {
+		http.Error(w, err.Error(), providerStatus(err))
+		return
+	}
+	if ec == nil {
+		http.Error(w, fmt.Sprintf("error code %q not found", vars["code"]), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ec)
+}
+
+// providerStatus: provider desconhecido no path é recurso inexistente (404); o resto, 400.
+func providerStatus(err error) int {
+	if errors.Is(err, rag.ErrUnknownProvider) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// Search devolve os trechos encontrados para a consulta, sem gerar resposta.
+// Aceita GET (?q=...&provider=...&topK=...&searchMode=...&minSimilarity=...) ou POST com JSON.
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	var req rag.SearchRequest
+
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query = q.Get("q")
+		if req.Query == "" {
+			req.Query = q.Get("query")
+		}
+		if p := q.Get("provider"); p != "" {
+			provider := rag.Provider(p)
+			req.Provider = &provider
+		}
+		req.SearchMode = rag.SearchMode(q.Get("searchMode"))
+		if v := q.Get("topK"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, "invalid topK", http.StatusBadRequest)
+				return
+			}
+			req.TopK = n
+		}
+		if v := q.Get("minSimilarity"); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				http.Error(w, "invalid minSimilarity", http.StatusBadRequest)
+				return
+			}
+			req.MinSimilarity = &f
+		}
+		for _, st := range queryList(q["sectionType"]) {
+			req.Filters.SectionTypes = append(req.Filters.SectionTypes, rag.SectionType(st))
+		}
+		req.Filters.TagsAll = queryList(q["tags"])
+		req.Filters.TagsAny = queryList(q["anyTags"])
+		req.Filters.APIVersion = q.Get("apiVersion")
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	resp, err := h.ragService.Search(ctx, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// AskStream responde via Server-Sent Events: "sources" com os trechos usados,
+// vários "token" com a resposta conforme o modelo gera e "done" com o uso de tokens.
+// Erros depois do início do stream chegam como evento "error".
+func (h *Handler) AskStream(w http.ResponseWriter, r *http.Request) {
+	var req rag.AskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.askTimeout)
+	defer cancel()
+
+	if req.Lang == "" {
+		req.Lang = "auto"
+	}
+
+	started := false
+	emit := func(ev rag.StreamEvent) error {
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Connection", "keep-alive")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		return writeSSE(w, flusher, ev)
+	}
+
+	if err := h.ragService.AskStream(ctx, req, emit); err != nil {
+		if !started {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_ = writeSSE(w, flusher, rag.StreamEvent{Type: rag.StreamError, Error: err.Error()})
+	}
+}
+
+func writeSSE(w http.ResponseWriter, flusher http.Flusher, ev rag.StreamEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
+
+// queryList aceita o parâmetro repetido (?tags=a&tags=b) ou separado por vírgula (?tags=a,b).
+func queryList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				out = append(out, item)
+			}
+		}
+	}
+	return out
+}
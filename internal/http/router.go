@@ -11,6 +11,11 @@ func NewRouter(h *Handler) http.Handler {
 
 	r.HandleFunc("/health", h.Health).Methods(http.MethodGet)
 	r.HandleFunc("/ask", h.Ask).Methods(http.MethodPost)
+	r.HandleFunc("/ask/stream", h.AskStream).Methods(http.MethodPost)
+	r.HandleFunc("/search", h.Search).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/providers", h.Providers).Methods(http.MethodGet)
+	r.HandleFunc("/providers/{provider}/endpoints", h.Endpoints).Methods(http.MethodGet)
+	r.HandleFunc("/providers/{provider}/errors/{code}", h.ErrorCode).Methods(http.MethodGet)
 
 	return r
 }
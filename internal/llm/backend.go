@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/config"
+	"github.com/josinaldojr/payment-gateway-rag/internal/llm/fake"
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+)
+
+// Backend agrupa o que a API e o importador precisam do provedor de modelos.
+// Hoje os três campos apontam para o mesmo client.
+type Backend struct {
+	Name       string
+	Embeddings rag.EmbeddingsClient
+	LLM        rag.LLMClient
+	Completer  Completer
+}
+
+// NewBackend cria o client escolhido por LLM_BACKEND (gemini | openai | fake).
+func NewBackend(ctx context.Context, cfg *config.Config) (*Backend, error) {
+	switch cfg.LLMBackend {
+	case "", "gemini":
+		if cfg.EmbeddingDim != embedDim {
+			return nil, fmt.Errorf("EMBEDDING_DIM=%d not supported by gemini backend (uses %d)", cfg.EmbeddingDim, embedDim)
+		}
+		g, err := NewGeminiClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &Backend{Name: "gemini", Embeddings: g, LLM: g, Completer: g}, nil
+
+	case "openai":
+		o, err := NewOpenAIClient(OpenAIConfig{
+			BaseURL:        cfg.OpenAIBaseURL,
+			APIKey:         cfg.OpenAIAPIKey,
+			ChatModel:      cfg.OpenAIChatModel,
+			EmbeddingModel: cfg.OpenAIEmbeddingModel,
+			Dimensions:     cfg.EmbeddingDim,
+			SendDimensions: cfg.OpenAISendDimensions,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &Backend{Name: "openai", Embeddings: o, LLM: o, Completer: o}, nil
+
+	case "fake":
+		f, err := fake.New(cfg.EmbeddingDim)
+		if err != nil {
+			return nil, err
+		}
+		return &Backend{Name: "fake", Embeddings: f, LLM: f, Completer: f}, nil
+
+	default:
+		return nil, fmt.Errorf("invalid LLM_BACKEND %q (use gemini, openai ou fake)", cfg.LLMBackend)
+	}
+}
@@ -0,0 +1,219 @@
+// Package fake é um backend de LLM determinístico e offline, para desenvolvimento e testes.
+// Embeddings são hashing de tokens (textos com palavras em comum ficam próximos) e a
+// resposta é montada extraindo os trechos recuperados, sem nenhum modelo.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+)
+
+const (
+	// maxAnswerChunks/maxExcerptChars limitam o tamanho da resposta extrativa.
+	maxAnswerChunks = 3
+	maxExcerptChars = 300
+)
+
+type Client struct {
+	dim int
+}
+
+func New(dim int) (*Client, error) {
+	if dim <= 0 {
+		return nil, fmt.Errorf("invalid embedding dimensions %d", dim)
+	}
+	return &Client{dim: dim}, nil
+}
+
+// Embed espalha cada token (e cada par de tokens vizinhos) num bucket do vetor via FNV,
+// com sinal também vindo do hash, e normaliza para norma 1.
+func (c *Client) Embed(_ context.Context, text string) ([]float32, error) {
+	tokens := tokenize(text)
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty text for embedding")
+	}
+
+	vec := make([]float32, c.dim)
+	add := func(feature string, weight float32) {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(feature))
+		sum := h.Sum64()
+		sign := float32(1)
+		if sum>>63 == 1 {
+			sign = -1
+		}
+		vec[sum%uint64(c.dim)] += sign * weight
+	}
+	for i, t := range tokens {
+		add(t, 1)
+		if i > 0 {
+			add(tokens[i-1]+" "+t, 0.5)
+		}
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		vec[0] = 1
+		return vec, nil
+	}
+	inv := float32(1 / math.Sqrt(norm))
+	for i := range vec {
+		vec[i] *= inv
+	}
+	return vec, nil
+}
+
+func (c *Client) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, 0, len(texts))
+	for i, t := range texts {
+		vec, err := c.Embed(ctx, t)
+		if err != nil {
+			return nil, fmt.Errorf("index %d: %w", i, err)
+		}
+		out = append(out, vec)
+	}
+	return out, nil
+}
+
+func (c *Client) GenerateAnswer(
+	_ context.Context,
+	question string,
+	_ []rag.ConversationMessage,
+	chunks []rag.DocChunk,
+	provider rag.Provider,
+	lang string,
+) (string, error) {
+	return extractiveAnswer(question, chunks, provider, lang), nil
+}
+
+// GenerateAnswerStream emite a mesma resposta do GenerateAnswer, palavra a palavra.
+func (c *Client) GenerateAnswerStream(
+	ctx context.Context,
+	question string,
+	history []rag.ConversationMessage,
+	chunks []rag.DocChunk,
+	provider rag.Provider,
+	lang string,
+	onToken func(string) error,
+) (*rag.Usage, error) {
+	answer, _ := c.GenerateAnswer(ctx, question, history, chunks, provider, lang)
+
+	words := strings.SplitAfter(answer, " ")
+	for _, w := range words {
+		if err := onToken(w); err != nil {
+			return nil, err
+		}
+	}
+
+	prompt := len(tokenize(question))
+	for _, ch := range chunks {
+		prompt += len(tokenize(ch.Content))
+	}
+	return &rag.Usage{
+		PromptTokens:     prompt,
+		CompletionTokens: len(words),
+		TotalTokens:      prompt + len(words),
+	}, nil
+}
+
+// CondenseQuestion junta a última pergunta do usuário ao follow-up, o que já basta
+// para a busca por hashing achar os mesmos termos.
+func (c *Client) CondenseQuestion(_ context.Context, history []rag.ConversationMessage, question string) (string, error) {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == rag.RoleUser {
+			return strings.TrimSpace(history[i].Content) + " " + strings.TrimSpace(question), nil
+		}
+	}
+	return question, nil
+}
+
+// Complete não tem modelo por trás: devolve um JSON vazio (o LLMReranker zera os scores
+// e mantém a ordem) ou texto vazio.
+func (c *Client) Complete(_ context.Context, _ string, jsonOutput bool) (string, error) {
+	if jsonOutput {
+		return `{"scores": []}`, nil
+	}
+	return "", nil
+}
+
+func extractiveAnswer(question string, chunks []rag.DocChunk, provider rag.Provider, lang string) string {
+	pt := lang != "en"
+
+	if len(chunks) == 0 {
+		if pt {
+			return "Não encontrei informação relevante na documentação indexada para esta pergunta."
+		}
+		return "I couldn't find any relevant information in the indexed documentation for this question."
+	}
+
+	var b strings.Builder
+	if provider == "" {
+		// comparação: uma seção por provider, com até maxAnswerChunks trechos cada
+		if pt {
+			fmt.Fprintf(&b, "Comparação sobre \"%s\":\n", strings.TrimSpace(question))
+		} else {
+			fmt.Fprintf(&b, "Comparison regarding \"%s\":\n", strings.TrimSpace(question))
+		}
+		var current rag.Provider
+		n := 0
+		for i, ch := range chunks {
+			if ch.Provider != current {
+				current, n = ch.Provider, 0
+				fmt.Fprintf(&b, "\n%s:", current)
+			}
+			if n == maxAnswerChunks {
+				continue
+			}
+			n++
+			fmt.Fprintf(&b, "\n- %s: %s [%d]", ch.Title, excerpt(ch.Content), i+1)
+		}
+		return b.String()
+	}
+
+	if pt {
+		fmt.Fprintf(&b, "Segundo a documentação de %s, sobre \"%s\":\n", provider, strings.TrimSpace(question))
+	} else {
+		fmt.Fprintf(&b, "According to the %s documentation, regarding \"%s\":\n", provider, strings.TrimSpace(question))
+	}
+
+	for i, ch := range chunks {
+		if i == maxAnswerChunks {
+			break
+		}
+		fmt.Fprintf(&b, "\n- %s: %s [%d]", ch.Title, excerpt(ch.Content), i+1)
+	}
+
+	return b.String()
+}
+
+// excerpt pega o começo do conteúdo numa linha só, cortado numa fronteira de palavra.
+func excerpt(content string) string {
+	s := strings.Join(strings.Fields(content), " ")
+	runes := []rune(s)
+	if len(runes) <= maxExcerptChars {
+		return s
+	}
+	cut := string(runes[:maxExcerptChars])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return cut + "..."
+}
+
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+var _ rag.EmbeddingsClient = (*Client)(nil)
+var _ rag.LLMClient = (*Client)(nil)
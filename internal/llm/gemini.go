@@ -14,6 +14,9 @@ const (
 	embeddingModel = "models/text-embedding-004"
 	ragChatModel   = "gemini-2.5-flash"
 	embedDim       = 768
+
+	// máximo de textos por chamada de EmbedContent (batchEmbedContents) na Gemini API
+	embedBatchSize = 100
 )
 
 type GeminiClient struct {
@@ -62,13 +65,63 @@ func (g *GeminiClient) Embed(ctx context.Context, text string) ([]float32, error
 		return nil, fmt.Errorf("no embeddings returned")
 	}
 
-	values := resp.Embeddings[0].Values
-	if len(values) != embedDim {
-		return nil, fmt.Errorf("unexpected embedding size %d (expected %d)", len(values), embedDim)
+	return toVector(resp.Embeddings[0])
+}
+
+// EmbedBatch manda vários textos por chamada, em lotes de até embedBatchSize.
+func (g *GeminiClient) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, 0, len(texts))
+
+	for start := 0; start < len(texts); start += embedBatchSize {
+		end := min(start+embedBatchSize, len(texts))
+
+		contents := make([]*genai.Content, 0, end-start)
+		for i := start; i < end; i++ {
+			clean := normalizeWhitespace(texts[i])
+			if clean == "" {
+				return nil, fmt.Errorf("empty text for embedding (index %d)", i)
+			}
+			contents = append(contents, genai.NewContentFromText(clean, genai.RoleUser))
+		}
+
+		resp, err := g.client.Models.EmbedContent(
+			ctx,
+			embeddingModel,
+			contents,
+			&genai.EmbedContentConfig{
+				OutputDimensionality: genai.Ptr(int32(embedDim)),
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("gemini embed batch error: %w", err)
+		}
+		if len(resp.Embeddings) != len(contents) {
+			return nil, fmt.Errorf("got %d embeddings for %d texts", len(resp.Embeddings), len(contents))
+		}
+
+		for _, e := range resp.Embeddings {
+			vec, err := toVector(e)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, vec)
+		}
+	}
+
+	return out, nil
+}
+
+func toVector(e *genai.ContentEmbedding) ([]float32, error) {
+	if e == nil || len(e.Values) != embedDim {
+		size := 0
+		if e != nil {
+			size = len(e.Values)
+		}
+		return nil, fmt.Errorf("unexpected embedding size %d (expected %d)", size, embedDim)
 	}
 
 	out := make([]float32, embedDim)
-	for i, v := range values {
+	for i, v := range e.Values {
 		out[i] = float32(v)
 	}
 	return out, nil
@@ -77,30 +130,21 @@ func (g *GeminiClient) Embed(ctx context.Context, text string) ([]float32, error
 func (g *GeminiClient) GenerateAnswer(
 	ctx context.Context,
 	question string,
+	history []rag.ConversationMessage,
 	chunks []rag.DocChunk,
 	provider rag.Provider,
 	lang string,
 ) (string, error) {
 	if len(chunks) == 0 {
-		return "I couldn't find any relevant information in the indexed documentation for this question.", nil
-	}
-
-	systemPrompt, contextText := buildSystemPrompt(provider, chunks, lang)
-
-	cfg := &genai.GenerateContentConfig{
-		SystemInstruction: genai.Text(systemPrompt)[0],
+		return noContextAnswer, nil
 	}
 
-	userContent := fmt.Sprintf(
-		"Question:\n%s\n\nRelevant documentation excerpts:\n%s",
-		strings.TrimSpace(question),
-		contextText,
-	)
+	contents, cfg := answerRequest(question, history, chunks, provider, lang)
 
 	resp, err := g.client.Models.GenerateContent(
 		ctx,
 		ragChatModel,
-		genai.Text(userContent),
+		contents,
 		cfg,
 	)
 	if err != nil {
@@ -119,99 +163,110 @@ func (g *GeminiClient) GenerateAnswer(
 	return txt, nil
 }
 
-// -------- helpers --------
+// GenerateAnswerStream usa a API de streaming do Gemini, repassando cada pedaço de texto para onToken.
+func (g *GeminiClient) GenerateAnswerStream(
+	ctx context.Context,
+	question string,
+	history []rag.ConversationMessage,
+	chunks []rag.DocChunk,
+	provider rag.Provider,
+	lang string,
+	onToken func(string) error,
+) (*rag.Usage, error) {
+	if len(chunks) == 0 {
+		return &rag.Usage{}, onToken(noContextAnswer)
+	}
 
-func buildSystemPrompt(provider rag.Provider, chunks []rag.DocChunk, lang string) (string, string) {
-	var sys strings.Builder
-	var ctx strings.Builder
-
-	target := map[string]string{
-		"pt": "Brazilian Portuguese",
-		"en": "English",
-		"es": "Spanish",
-	}[lang]
-	if target == "" {
-		target = "Brazilian Portuguese"
-	}
-
-	sys.WriteString("You are a technical assistant specialized in payment gateway integrations for ")
-	sys.WriteString(string(provider))
-	sys.WriteString(". ")
-	sys.WriteString(target)
-	sys.WriteString(" is the target language for all responses. ")
-	sys.WriteString("Always answer ONLY based on the provided documentation excerpts. ")
-	sys.WriteString("If the answer is not clearly present, say that it is not available in the indexed documentation. ")
-	sys.WriteString("Do not invent endpoints, URLs, fields or values. ")
-	sys.WriteString("When possible, structure the answer as:\n")
-	sys.WriteString("- Operation flow\n")
-	sys.WriteString("- Endpoint(s)\n")
-	sys.WriteString("- Required and optional parameters\n")
-	sys.WriteString("- Example request/response\n")
-	sys.WriteString("- Important notes (3DS, capture, refunds, error codes, etc.)\n")
-
-	const (
-		maxChunks     = 10
-		maxChunkChars = 1200
-	)
+	contents, cfg := answerRequest(question, history, chunks, provider, lang)
 
-	n := len(chunks)
-	if n > maxChunks {
-		n = maxChunks
+	usage := &rag.Usage{}
+	for resp, err := range g.client.Models.GenerateContentStream(ctx, ragChatModel, contents, cfg) {
+		if err != nil {
+			return nil, fmt.Errorf("gemini generateContentStream error: %w", err)
+		}
+		if resp == nil {
+			continue
+		}
+		if t := resp.Text(); t != "" {
+			if err := onToken(t); err != nil {
+				return nil, err
+			}
+		}
+		// o último pedaço traz o total; os anteriores trazem parciais
+		if m := resp.UsageMetadata; m != nil {
+			usage.PromptTokens = int(m.PromptTokenCount)
+			usage.CompletionTokens = int(m.CandidatesTokenCount)
+			usage.TotalTokens = int(m.TotalTokenCount)
+		}
 	}
 
-	for i := 0; i < n; i++ {
-		c := chunks[i]
-		ctx.WriteString(fmt.Sprintf(
-			"\n[DOC %d] title=%s source=%s\n",
-			c.ID,
-			oneLine(c.Title),
-			c.SourceURL,
-		))
-		ctx.WriteString(trimBody(c.Content, maxChunkChars))
-		ctx.WriteString("\n----\n")
+	return usage, nil
+}
+
+// CondenseQuestion transforma um follow-up em pergunta autossuficiente para a busca vetorial.
+func (g *GeminiClient) CondenseQuestion(ctx context.Context, history []rag.ConversationMessage, question string) (string, error) {
+	if len(history) == 0 {
+		return question, nil
 	}
 
-	return sys.String(), ctx.String()
+	condensed, err := g.Complete(ctx, condensePrompt(history, question), false)
+	if err != nil {
+		return "", fmt.Errorf("gemini condense error: %w", err)
+	}
+	if condensed == "" {
+		return question, nil
+	}
+	return condensed, nil
 }
 
-func normalizeWhitespace(s string) string {
-	s = strings.TrimSpace(s)
-	if s == "" {
-		return s
-	}
-	var b strings.Builder
-	b.Grow(len(s))
-	space := false
-	for _, r := range s {
-		if r == ' ' || r == '\n' || r == '\r' || r == '\t' {
-			if !space {
-				b.WriteRune(' ')
-				space = true
-			}
-		} else {
-			b.WriteRune(r)
-			space = false
-		}
+// Complete é uma chamada simples prompt → texto (temperatura 0), usada pelo LLMReranker e pela condensação.
+func (g *GeminiClient) Complete(ctx context.Context, prompt string, jsonOutput bool) (string, error) {
+	cfg := &genai.GenerateContentConfig{Temperature: genai.Ptr(float32(0))}
+	if jsonOutput {
+		cfg.ResponseMIMEType = "application/json"
 	}
-	return b.String()
+
+	resp, err := g.client.Models.GenerateContent(ctx, ragChatModel, genai.Text(prompt), cfg)
+	if err != nil {
+		return "", fmt.Errorf("gemini generateContent error: %w", err)
+	}
+	if resp == nil {
+		return "", fmt.Errorf("empty response from gemini")
+	}
+	return strings.TrimSpace(resp.Text()), nil
 }
 
-func oneLine(s string) string {
-	s = strings.ReplaceAll(s, "\n", " ")
-	s = strings.TrimSpace(s)
-	if len(s) > 160 {
-		return s[:160] + "..."
+// -------- helpers --------
+
+// answerRequest monta o histórico + pergunta atual (com os trechos) e a config
+// (system prompt) usados tanto na geração normal quanto na em streaming.
+func answerRequest(question string, history []rag.ConversationMessage, chunks []rag.DocChunk, provider rag.Provider, lang string) ([]*genai.Content, *genai.GenerateContentConfig) {
+	systemPrompt, contextText := buildSystemPrompt(provider, chunks, lang)
+
+	cfg := &genai.GenerateContentConfig{
+		SystemInstruction: genai.Text(systemPrompt)[0],
 	}
-	return s
+
+	contents := historyContents(history)
+	contents = append(contents, genai.NewContentFromText(answerUserPrompt(question, contextText), genai.RoleUser))
+
+	return contents, cfg
 }
 
-func trimBody(s string, max int) string {
-	s = strings.TrimSpace(s)
-	if len(s) <= max {
-		return s
+// historyContents converte os turnos anteriores para o formato multi-turn do Gemini.
+// Respostas antigas são cortadas para não estourar o contexto.
+func historyContents(history []rag.ConversationMessage) []*genai.Content {
+	contents := make([]*genai.Content, 0, len(history)+1)
+	for _, m := range history {
+		role := genai.Role(genai.RoleUser)
+		if m.Role == rag.RoleAssistant {
+			role = genai.RoleModel
+		}
+		contents = append(contents, genai.NewContentFromText(trimBody(m.Content, maxTurnChars), role))
 	}
-	return s[:max] + "..."
+	return contents
 }
 
 var _ rag.EmbeddingsClient = (*GeminiClient)(nil)
 var _ rag.LLMClient = (*GeminiClient)(nil)
+var _ Completer = (*GeminiClient)(nil)
@@ -0,0 +1,145 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+type geminiEmbedRequest struct {
+	Requests []struct {
+		Model   string `json:"model"`
+		Content struct {
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"content"`
+		OutputDimensionality int `json:"outputDimensionality"`
+	} `json:"requests"`
+}
+
+type geminiEmbedding struct {
+	Values []float32 `json:"values"`
+}
+
+// newGeminiTestClient aponta o SDK para um servidor fake; embed recebe os textos
+// de cada chamada a batchEmbedContents e devolve os embeddings da resposta.
+func newGeminiTestClient(t *testing.T, embed func(texts []string) []geminiEmbedding) *GeminiClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/models/text-embedding-004:batchEmbedContents") {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		var req geminiEmbedRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		texts := make([]string, len(req.Requests))
+		for i, rr := range req.Requests {
+			if rr.OutputDimensionality != embedDim || len(rr.Content.Parts) != 1 {
+				t.Errorf("request = %+v", rr)
+			}
+			texts[i] = rr.Content.Parts[0].Text
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"embeddings": embed(texts)})
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: srv.URL},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &GeminiClient{client: c}
+}
+
+// vectorFor devolve um embedding de embedDim posições com o número do texto ("t7" → 7) na primeira.
+func vectorFor(text string) geminiEmbedding {
+	n, _ := strconv.Atoi(strings.TrimPrefix(text, "t"))
+	v := make([]float32, embedDim)
+	v[0] = float32(n)
+	return geminiEmbedding{Values: v}
+}
+
+func TestGeminiEmbedBatch(t *testing.T) {
+	var sizes []int
+	g := newGeminiTestClient(t, func(texts []string) []geminiEmbedding {
+		sizes = append(sizes, len(texts))
+		out := make([]geminiEmbedding, len(texts))
+		for i, text := range texts {
+			out[i] = vectorFor(text)
+		}
+		return out
+	})
+
+	texts := make([]string, 2*embedBatchSize+50)
+	for i := range texts {
+		texts[i] = fmt.Sprintf(" t%d\n", i)
+	}
+	vecs, err := g.EmbedBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(sizes) != fmt.Sprint([]int{embedBatchSize, embedBatchSize, 50}) {
+		t.Errorf("batch sizes = %v", sizes)
+	}
+	if len(vecs) != len(texts) {
+		t.Fatalf("got %d vectors for %d texts", len(vecs), len(texts))
+	}
+	for i, v := range vecs {
+		if v[0] != float32(i) {
+			t.Fatalf("vecs[%d][0] = %v, out of order", i, v[0])
+		}
+	}
+}
+
+func TestGeminiEmbedBatchErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+		embed func(texts []string) []geminiEmbedding
+		want  string
+	}{
+		{
+			name:  "count mismatch",
+			texts: []string{"t1", "t2"},
+			embed: func([]string) []geminiEmbedding { return []geminiEmbedding{vectorFor("t1")} },
+			want:  "got 1 embeddings for 2 texts",
+		},
+		{
+			name:  "wrong size",
+			texts: []string{"t1"},
+			embed: func([]string) []geminiEmbedding { return []geminiEmbedding{{Values: []float32{1, 2}}} },
+			want:  "unexpected embedding size 2",
+		},
+		{
+			name:  "empty text",
+			texts: []string{"t1", "  \n"},
+			embed: func([]string) []geminiEmbedding {
+				t.Error("empty text must fail before the request")
+				return nil
+			},
+			want: "empty text for embedding (index 1)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newGeminiTestClient(t, tt.embed)
+			_, err := g.EmbedBatch(context.Background(), tt.texts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
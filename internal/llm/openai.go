@@ -0,0 +1,367 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+)
+
+// openAIEmbedBatchSize: servidores locais (Ollama, vLLM) costumam aceitar lotes bem menores que a API oficial.
+const openAIEmbedBatchSize = 64
+
+// OpenAIConfig configura um servidor compatível com a API da OpenAI
+// (/v1/chat/completions e /v1/embeddings): OpenAI, Ollama, vLLM, LM Studio...
+type OpenAIConfig struct {
+	BaseURL        string // ex: http://localhost:11434/v1
+	APIKey         string // opcional em servidores locais
+	ChatModel      string
+	EmbeddingModel string
+
+	// Dimensions é a dimensão esperada dos embeddings (a coluna vector do banco).
+	// Com SendDimensions ela vai no request (modelos text-embedding-3-*); senão só é validada.
+	Dimensions     int
+	SendDimensions bool
+}
+
+type OpenAIClient struct {
+	cfg  OpenAIConfig
+	http *http.Client
+}
+
+func NewOpenAIClient(cfg OpenAIConfig) (*OpenAIClient, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("missing OPENAI_BASE_URL")
+	}
+	if cfg.ChatModel == "" || cfg.EmbeddingModel == "" {
+		return nil, fmt.Errorf("missing OPENAI_CHAT_MODEL or OPENAI_EMBEDDING_MODEL")
+	}
+	if cfg.Dimensions <= 0 {
+		return nil, fmt.Errorf("invalid embedding dimensions %d", cfg.Dimensions)
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+
+	// sem timeout global: o streaming pode durar minutos; o limite vem do ctx de cada chamada
+	return &OpenAIClient{cfg: cfg, http: &http.Client{}}, nil
+}
+
+// HTTPError é uma resposta não-2xx do servidor compatível com OpenAI.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("http %d: %s", e.StatusCode, e.Body)
+}
+
+// -------- embeddings --------
+
+type openAIEmbeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (o *OpenAIClient) Embed(ctx context.Context, text string) ([]float32, error) {
+	vecs, err := o.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vecs[0], nil
+}
+
+// EmbedBatch manda vários textos por chamada, em lotes de até openAIEmbedBatchSize.
+func (o *OpenAIClient) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, 0, len(texts))
+
+	for start := 0; start < len(texts); start += openAIEmbedBatchSize {
+		end := min(start+openAIEmbedBatchSize, len(texts))
+
+		req := openAIEmbeddingRequest{Model: o.cfg.EmbeddingModel}
+		for i := start; i < end; i++ {
+			clean := normalizeWhitespace(texts[i])
+			if clean == "" {
+				return nil, fmt.Errorf("empty text for embedding (index %d)", i)
+			}
+			req.Input = append(req.Input, clean)
+		}
+		if o.cfg.SendDimensions {
+			req.Dimensions = o.cfg.Dimensions
+		}
+
+		var resp openAIEmbeddingResponse
+		if err := o.postJSON(ctx, "/embeddings", req, &resp); err != nil {
+			return nil, fmt.Errorf("openai embed error: %w", err)
+		}
+		if len(resp.Data) != len(req.Input) {
+			return nil, fmt.Errorf("got %d embeddings for %d texts", len(resp.Data), len(req.Input))
+		}
+
+		batch := make([][]float32, len(req.Input))
+		for _, d := range resp.Data {
+			if d.Index < 0 || d.Index >= len(batch) {
+				return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+			}
+			if len(d.Embedding) != o.cfg.Dimensions {
+				return nil, fmt.Errorf("unexpected embedding size %d (expected %d)", len(d.Embedding), o.cfg.Dimensions)
+			}
+			batch[d.Index] = d.Embedding
+		}
+		out = append(out, batch...)
+	}
+
+	return out, nil
+}
+
+// -------- chat --------
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIChatRequest struct {
+	Model          string            `json:"model"`
+	Messages       []openAIMessage   `json:"messages"`
+	Temperature    *float32          `json:"temperature,omitempty"`
+	ResponseFormat *openAIRespFormat `json:"response_format,omitempty"`
+	Stream         bool              `json:"stream,omitempty"`
+	StreamOptions  *openAIStreamOpts `json:"stream_options,omitempty"`
+}
+
+type openAIRespFormat struct {
+	Type string `json:"type"`
+}
+
+type openAIStreamOpts struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+		Delta   openAIMessage `json:"delta"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+func (o *OpenAIClient) GenerateAnswer(
+	ctx context.Context,
+	question string,
+	history []rag.ConversationMessage,
+	chunks []rag.DocChunk,
+	provider rag.Provider,
+	lang string,
+) (string, error) {
+	if len(chunks) == 0 {
+		return noContextAnswer, nil
+	}
+
+	req := openAIChatRequest{
+		Model:    o.cfg.ChatModel,
+		Messages: answerMessages(question, history, chunks, provider, lang),
+	}
+
+	txt, err := o.chat(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if txt == "" {
+		return "", fmt.Errorf("model returned empty text")
+	}
+	return txt, nil
+}
+
+// GenerateAnswerStream usa stream=true (SSE) do chat/completions, repassando cada delta para onToken.
+func (o *OpenAIClient) GenerateAnswerStream(
+	ctx context.Context,
+	question string,
+	history []rag.ConversationMessage,
+	chunks []rag.DocChunk,
+	provider rag.Provider,
+	lang string,
+	onToken func(string) error,
+) (*rag.Usage, error) {
+	if len(chunks) == 0 {
+		return &rag.Usage{}, onToken(noContextAnswer)
+	}
+
+	req := openAIChatRequest{
+		Model:         o.cfg.ChatModel,
+		Messages:      answerMessages(question, history, chunks, provider, lang),
+		Stream:        true,
+		StreamOptions: &openAIStreamOpts{IncludeUsage: true},
+	}
+
+	resp, err := o.post(ctx, "/chat/completions", req)
+	if err != nil {
+		return nil, fmt.Errorf("openai chat stream error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	usage := &rag.Usage{}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("invalid stream chunk: %w", err)
+		}
+		for _, c := range chunk.Choices {
+			if c.Delta.Content == "" {
+				continue
+			}
+			if err := onToken(c.Delta.Content); err != nil {
+				return nil, err
+			}
+		}
+		// com include_usage o último chunk (choices vazio) traz o total
+		if u := chunk.Usage; u != nil {
+			usage.PromptTokens = u.PromptTokens
+			usage.CompletionTokens = u.CompletionTokens
+			usage.TotalTokens = u.TotalTokens
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("openai chat stream error: %w", err)
+	}
+
+	return usage, nil
+}
+
+// CondenseQuestion transforma um follow-up em pergunta autossuficiente para a busca vetorial.
+func (o *OpenAIClient) CondenseQuestion(ctx context.Context, history []rag.ConversationMessage, question string) (string, error) {
+	if len(history) == 0 {
+		return question, nil
+	}
+
+	condensed, err := o.Complete(ctx, condensePrompt(history, question), false)
+	if err != nil {
+		return "", fmt.Errorf("openai condense error: %w", err)
+	}
+	if condensed == "" {
+		return question, nil
+	}
+	return condensed, nil
+}
+
+// Complete é uma chamada simples prompt → texto (temperatura 0), usada pelo LLMReranker e pela condensação.
+// jsonOutput usa response_format json_object, que exige objeto na raiz e alguns servidores ignoram;
+// por isso o prompt também pede JSON e descreve o objeto.
+func (o *OpenAIClient) Complete(ctx context.Context, prompt string, jsonOutput bool) (string, error) {
+	var zero float32
+	req := openAIChatRequest{
+		Model:       o.cfg.ChatModel,
+		Messages:    []openAIMessage{{Role: "user", Content: prompt}},
+		Temperature: &zero,
+	}
+	if jsonOutput {
+		req.ResponseFormat = &openAIRespFormat{Type: "json_object"}
+	}
+	return o.chat(ctx, req)
+}
+
+func (o *OpenAIClient) chat(ctx context.Context, req openAIChatRequest) (string, error) {
+	var resp openAIChatResponse
+	if err := o.postJSON(ctx, "/chat/completions", req, &resp); err != nil {
+		return "", fmt.Errorf("openai chat error: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("empty response from openai")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
+
+// -------- helpers --------
+
+// answerMessages monta system prompt + histórico + pergunta atual (com os trechos).
+func answerMessages(question string, history []rag.ConversationMessage, chunks []rag.DocChunk, provider rag.Provider, lang string) []openAIMessage {
+	systemPrompt, contextText := buildSystemPrompt(provider, chunks, lang)
+
+	msgs := make([]openAIMessage, 0, len(history)+2)
+	msgs = append(msgs, openAIMessage{Role: "system", Content: systemPrompt})
+	for _, m := range history {
+		role := "user"
+		if m.Role == rag.RoleAssistant {
+			role = "assistant"
+		}
+		msgs = append(msgs, openAIMessage{Role: role, Content: trimBody(m.Content, maxTurnChars)})
+	}
+	msgs = append(msgs, openAIMessage{Role: "user", Content: answerUserPrompt(question, contextText)})
+
+	return msgs
+}
+
+func (o *OpenAIClient) postJSON(ctx context.Context, path string, body, out any) error {
+	resp, err := o.post(ctx, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s response: %w", path, err)
+	}
+	return nil
+}
+
+// post faz o request e devolve a resposta só se for 2xx; o chamador fecha o Body.
+func (o *OpenAIClient) post(ctx context.Context, path string, body any) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.cfg.BaseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.cfg.APIKey)
+	}
+
+	resp, err := o.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(msg))}
+	}
+	return resp, nil
+}
+
+var _ rag.EmbeddingsClient = (*OpenAIClient)(nil)
+var _ rag.LLMClient = (*OpenAIClient)(nil)
+var _ Completer = (*OpenAIClient)(nil)
@@ -0,0 +1,247 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+)
+
+// newOpenAITestClient sobe um servidor fake da API e devolve o client apontando para ele.
+func newOpenAITestClient(t *testing.T, dim int, handler http.HandlerFunc) *OpenAIClient {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c, err := NewOpenAIClient(OpenAIConfig{
+		BaseURL:        srv.URL + "/v1/",
+		APIKey:         "sk-test",
+		ChatModel:      "chat-model",
+		EmbeddingModel: "embed-model",
+		Dimensions:     dim,
+		SendDimensions: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestOpenAIEmbedBatch(t *testing.T) {
+	var sizes []int
+	c := newOpenAITestClient(t, 2, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("request = %s %s (auth %q)", r.Method, r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req openAIEmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.Model != "embed-model" || req.Dimensions != 2 {
+			t.Errorf("request = %+v", req)
+		}
+		sizes = append(sizes, len(req.Input))
+
+		// devolve fora de ordem: o client reordena pelo index
+		var resp openAIEmbeddingResponse
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			n, _ := strconv.Atoi(strings.TrimPrefix(req.Input[i], "t"))
+			resp.Data = append(resp.Data, struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			}{Index: i, Embedding: []float32{float32(n), 0}})
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	texts := make([]string, openAIEmbedBatchSize+6)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("  t%d\n", i)
+	}
+	vecs, err := c.EmbedBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 2 || sizes[0] != openAIEmbedBatchSize || sizes[1] != 6 {
+		t.Errorf("batch sizes = %v", sizes)
+	}
+	if len(vecs) != len(texts) {
+		t.Fatalf("got %d vectors for %d texts", len(vecs), len(texts))
+	}
+	for i, v := range vecs {
+		if v[0] != float32(i) {
+			t.Fatalf("vecs[%d] = %v, out of order", i, v)
+		}
+	}
+
+	if _, err := c.EmbedBatch(context.Background(), []string{"t0", " "}); err == nil {
+		t.Error("expected error for empty text")
+	}
+}
+
+func TestOpenAIEmbedBatchMismatch(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"count", `[{"index":0,"embedding":[1,2]}]`, "got 1 embeddings for 2 texts"},
+		{"index", `[{"index":0,"embedding":[1,2]},{"index":5,"embedding":[1,2]}]`, "index 5 out of range"},
+		{"size", `[{"index":0,"embedding":[1,2]},{"index":1,"embedding":[1]}]`, "unexpected embedding size 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newOpenAITestClient(t, 2, func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"data":%s}`, tt.data)
+			})
+			_, err := c.EmbedBatch(context.Background(), []string{"a", "b"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAIChat(t *testing.T) {
+	var got openAIChatRequest
+	c := newOpenAITestClient(t, 2, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		got = openAIChatRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"  resposta [1]\n"}}]}`)
+	})
+
+	history := []rag.ConversationMessage{
+		{Role: rag.RoleUser, Content: "como capturar?"},
+		{Role: rag.RoleAssistant, Content: "use PUT"},
+	}
+	chunks := []rag.DocChunk{{Provider: rag.ProviderRede, Title: "Captura", Content: "PUT /v1/transactions/{tid}"}}
+	answer, err := c.GenerateAnswer(context.Background(), "e o estorno?", history, chunks, rag.ProviderRede, "pt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "resposta [1]" {
+		t.Errorf("answer = %q", answer)
+	}
+	roles := make([]string, len(got.Messages))
+	for i, m := range got.Messages {
+		roles[i] = m.Role
+	}
+	if strings.Join(roles, ",") != "system,user,assistant,user" || got.Model != "chat-model" || got.Stream || got.ResponseFormat != nil {
+		t.Errorf("request = %+v", got)
+	}
+	if last := got.Messages[len(got.Messages)-1].Content; !strings.Contains(last, "e o estorno?") || !strings.Contains(last, "PUT /v1/transactions/{tid}") {
+		t.Errorf("user prompt = %q", last)
+	}
+
+	// sem trechos não chama o modelo
+	got = openAIChatRequest{}
+	if answer, err := c.GenerateAnswer(context.Background(), "x", nil, nil, rag.ProviderRede, "pt"); err != nil || answer != noContextAnswer || got.Model != "" {
+		t.Errorf("no chunks: answer = %q, err = %v, request = %+v", answer, err, got)
+	}
+
+	// o modo JSON manda response_format e temperatura 0
+	if _, err := c.Complete(context.Background(), "prompt", true); err != nil {
+		t.Fatal(err)
+	}
+	if got.ResponseFormat == nil || got.ResponseFormat.Type != "json_object" || got.Temperature == nil || *got.Temperature != 0 {
+		t.Errorf("complete request = %+v", got)
+	}
+}
+
+func TestOpenAIStream(t *testing.T) {
+	c := newOpenAITestClient(t, 2, func(w http.ResponseWriter, r *http.Request) {
+		var req openAIChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream || req.StreamOptions == nil || !req.StreamOptions.IncludeUsage {
+			t.Errorf("request = %+v", req)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": keep-alive\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Use \"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"PUT [1]\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":10,\"completion_tokens\":3,\"total_tokens\":13}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"ignorado\"}}]}\n\n")
+	})
+
+	var tokens []string
+	chunks := []rag.DocChunk{{Title: "Captura", Content: "PUT"}}
+	usage, err := c.GenerateAnswerStream(context.Background(), "como capturar?", nil, chunks, rag.ProviderRede, "pt", func(tok string) error {
+		tokens = append(tokens, tok)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(tokens, "|") != "Use |PUT [1]" {
+		t.Errorf("tokens = %q", tokens)
+	}
+	if usage.PromptTokens != 10 || usage.CompletionTokens != 3 || usage.TotalTokens != 13 {
+		t.Errorf("usage = %+v", usage)
+	}
+
+	// erro do callback (cliente desconectou) interrompe o stream
+	stop := errors.New("stop")
+	if _, err := c.GenerateAnswerStream(context.Background(), "x", nil, chunks, rag.ProviderRede, "pt", func(string) error { return stop }); !errors.Is(err, stop) {
+		t.Errorf("err = %v, want %v", err, stop)
+	}
+}
+
+func TestOpenAIHTTPError(t *testing.T) {
+	c := newOpenAITestClient(t, 2, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error":{"message":"rate limit"}}`+"\n")
+	})
+
+	_, err := c.Embed(context.Background(), "texto")
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("err = %v, want *HTTPError", err)
+	}
+	if httpErr.StatusCode != http.StatusTooManyRequests || httpErr.Body != `{"error":{"message":"rate limit"}}` {
+		t.Errorf("http error = %+v", httpErr)
+	}
+	if !IsRetryable(err) {
+		t.Error("429 should be retryable")
+	}
+
+	_, err = c.GenerateAnswerStream(context.Background(), "x", nil, []rag.DocChunk{{Content: "a"}}, rag.ProviderRede, "pt", func(string) error { return nil })
+	if !errors.As(err, &httpErr) {
+		t.Errorf("stream err = %v, want *HTTPError", err)
+	}
+}
+
+// O modo json_object só devolve objeto na raiz: o LLMReranker precisa funcionar com ele.
+func TestOpenAIRerankJSONObject(t *testing.T) {
+	c := newOpenAITestClient(t, 2, func(w http.ResponseWriter, r *http.Request) {
+		var req openAIChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.ResponseFormat == nil || req.ResponseFormat.Type != "json_object" {
+			t.Errorf("response_format = %+v", req.ResponseFormat)
+		}
+		content, _ := json.Marshal(`{"scores": [{"doc": 1, "score": 2}, {"doc": 2, "score": 9}]}`)
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%s}}]}`, content)
+	})
+
+	chunks := []rag.DocChunk{{ID: 1, Content: "a"}, {ID: 2, Content: "b"}}
+	out, err := NewLLMReranker(c).Rerank(context.Background(), "pergunta", chunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out[0].ID != 2 || out[0].Score != 0.9 {
+		t.Errorf("reranked = %+v", out)
+	}
+}
@@ -0,0 +1,182 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+)
+
+// Prompts e helpers de texto compartilhados pelos backends (Gemini, OpenAI-compatível).
+
+// maxTurnChars: turnos antigos da conversa são cortados nisso para não estourar o contexto.
+const maxTurnChars = 2000
+
+// noContextAnswer é devolvida sem chamar o modelo quando não há trechos.
+const noContextAnswer = "I couldn't find any relevant information in the indexed documentation for this question."
+
+func answerUserPrompt(question, contextText string) string {
+	return fmt.Sprintf(
+		"Question:\n%s\n\nRelevant documentation excerpts:\n%s",
+		strings.TrimSpace(question),
+		contextText,
+	)
+}
+
+func condensePrompt(history []rag.ConversationMessage, question string) string {
+	var prompt strings.Builder
+	prompt.WriteString("Rewrite the follow-up question as a standalone question that can be understood without the conversation. ")
+	prompt.WriteString("Keep the same language as the follow-up. Keep gateway names, endpoints, field names and codes mentioned before. ")
+	prompt.WriteString("Reply ONLY with the rewritten question.\n\nConversation:\n")
+	for _, m := range history {
+		prompt.WriteString(fmt.Sprintf("%s: %s\n", m.Role, oneLine(trimBody(m.Content, 600))))
+	}
+	prompt.WriteString("\nFollow-up question: ")
+	prompt.WriteString(strings.TrimSpace(question))
+	return prompt.String()
+}
+
+func buildSystemPrompt(provider rag.Provider, chunks []rag.DocChunk, lang string) (string, string) {
+	var sys strings.Builder
+	var ctx strings.Builder
+
+	target := map[string]string{
+		"pt": "Brazilian Portuguese",
+		"en": "English",
+		"es": "Spanish",
+	}[lang]
+	if target == "" {
+		target = "Brazilian Portuguese"
+	}
+
+	compared := comparedProviders(provider, chunks)
+
+	sys.WriteString("You are a technical assistant specialized in payment gateway integrations for ")
+	if len(compared) > 0 {
+		sys.WriteString(strings.Join(compared, ", "))
+	} else {
+		sys.WriteString(string(provider))
+	}
+	sys.WriteString(". ")
+	sys.WriteString(target)
+	sys.WriteString(" is the target language for all responses. ")
+	sys.WriteString("Always answer ONLY based on the provided documentation excerpts. ")
+	sys.WriteString("If the answer is not clearly present, say that it is not available in the indexed documentation. ")
+	sys.WriteString("Do not invent endpoints, URLs, fields or values. ")
+	sys.WriteString("Cite the excerpts that support each statement with their number in square brackets right after it, ")
+	sys.WriteString("e.g. \"The capture endpoint is POST /v1/capture [2].\" or \"... [1][3]\". ")
+	sys.WriteString("Only use numbers of the excerpts provided and do not add a separate list of sources at the end. ")
+	if len(compared) > 0 {
+		// modo comparação: cada trecho diz de qual gateway é
+		sys.WriteString("The question compares gateways; each excerpt says which provider it documents. ")
+		sys.WriteString("Answer side by side: one section per gateway with how it works there, ")
+		sys.WriteString("then a short list of the key differences (endpoints, fields, flow, limits). ")
+		sys.WriteString("Never attribute an excerpt of one gateway to another. ")
+		sys.WriteString("If the excerpts do not cover a gateway mentioned in the question, say it is not in the indexed documentation for it.\n")
+	} else {
+		sys.WriteString("When possible, structure the answer as:\n")
+		sys.WriteString("- Operation flow\n")
+		sys.WriteString("- Endpoint(s)\n")
+		sys.WriteString("- Required and optional parameters\n")
+		sys.WriteString("- Example request/response\n")
+		sys.WriteString("- Important notes (3DS, capture, refunds, error codes, etc.)\n")
+	}
+
+	const maxChunkChars = 1200
+
+	n := min(len(chunks), rag.MaxPromptChunks)
+
+	for i := 0; i < n; i++ {
+		c := chunks[i]
+		if len(compared) > 0 {
+			ctx.WriteString(fmt.Sprintf(
+				"\n[%d] provider=%s title=%s source=%s\n",
+				i+1,
+				c.Provider,
+				oneLine(c.Title),
+				c.SourceURL,
+			))
+		} else {
+			ctx.WriteString(fmt.Sprintf(
+				"\n[%d] title=%s source=%s\n",
+				i+1,
+				oneLine(c.Title),
+				c.SourceURL,
+			))
+		}
+		ctx.WriteString(trimBody(c.Content, maxChunkChars))
+		ctx.WriteString("\n----\n")
+	}
+
+	return sys.String(), ctx.String()
+}
+
+// comparedProviders devolve os providers dos chunks, na ordem em que aparecem, quando
+// a resposta é uma comparação (provider vazio); nil fora desse modo.
+func comparedProviders(provider rag.Provider, chunks []rag.DocChunk) []string {
+	if provider != "" {
+		return nil
+	}
+	var out []string
+	seen := make(map[rag.Provider]bool)
+	for _, c := range chunks {
+		if !seen[c.Provider] {
+			seen[c.Provider] = true
+			out = append(out, string(c.Provider))
+		}
+	}
+	return out
+}
+
+func normalizeWhitespace(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\n' || r == '\r' || r == '\t' {
+			if !space {
+				b.WriteRune(' ')
+				space = true
+			}
+		} else {
+			b.WriteRune(r)
+			space = false
+		}
+	}
+	return b.String()
+}
+
+func oneLine(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	s = strings.TrimSpace(s)
+	if len(s) > 160 {
+		return s[:160] + "..."
+	}
+	return s
+}
+
+func trimBody(s string, max int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
+
+// stripCodeFence remove o ```json ... ``` que alguns modelos colocam em volta do JSON
+// mesmo quando pedimos só JSON.
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+)
+
+const rerankChunkChars = 800
+
+// Completer é uma chamada simples prompt → texto, implementada por todos os backends.
+// Com jsonOutput o modelo é instruído a responder só JSON.
+type Completer interface {
+	Complete(ctx context.Context, prompt string, jsonOutput bool) (string, error)
+}
+
+// LLMReranker pede ao LLM uma nota de relevância (0-10) para cada candidato
+// e reordena por ela. Mais caro que o rag.LexicalReranker, mas entende paráfrase.
+type LLMReranker struct {
+	llm Completer
+}
+
+func NewLLMReranker(c Completer) *LLMReranker {
+	return &LLMReranker{llm: c}
+}
+
+type rerankScore struct {
+	Doc   int     `json:"doc"`
+	Score float64 `json:"score"`
+}
+
+// rerankReply é um objeto (e não um array) porque o modo JSON da OpenAI
+// (response_format json_object) só aceita objeto na raiz.
+type rerankReply struct {
+	Scores []rerankScore `json:"scores"`
+}
+
+func (r *LLMReranker) Rerank(ctx context.Context, question string, chunks []rag.DocChunk) ([]rag.DocChunk, error) {
+	if len(chunks) == 0 {
+		return chunks, nil
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("Rate how useful each documentation excerpt is to answer the question, ")
+	prompt.WriteString("from 0 (irrelevant) to 10 (directly answers it). ")
+	prompt.WriteString("Reply ONLY with a JSON object like {\"scores\": [{\"doc\": 1, \"score\": 7}]}, one entry per excerpt.\n\n")
+	prompt.WriteString("Question:\n")
+	prompt.WriteString(strings.TrimSpace(question))
+	prompt.WriteString("\n\nExcerpts:\n")
+	for i, c := range chunks {
+		prompt.WriteString(fmt.Sprintf("\n[%d] title=%s\n", i+1, oneLine(c.Title)))
+		prompt.WriteString(trimBody(c.Content, rerankChunkChars))
+		prompt.WriteString("\n----\n")
+	}
+
+	text, err := r.llm.Complete(ctx, prompt.String(), true)
+	if err != nil {
+		return nil, fmt.Errorf("llm rerank error: %w", err)
+	}
+
+	scores, err := parseRerankScores(text)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]rag.DocChunk, len(chunks))
+	copy(out, chunks)
+	for i := range out {
+		out[i].Score = 0
+	}
+	for _, s := range scores {
+		if s.Doc < 1 || s.Doc > len(out) {
+			continue
+		}
+		out[s.Doc-1].Score = s.Score / 10
+	}
+
+	sort.SliceStable(out, func(a, b int) bool {
+		return out[a].Score > out[b].Score
+	})
+
+	return out, nil
+}
+
+// parseRerankScores lê {"scores": [...]}; aceita também o array puro, que alguns
+// modelos devolvem mesmo instruídos a responder com objeto.
+func parseRerankScores(text string) ([]rerankScore, error) {
+	raw := []byte(stripCodeFence(text))
+
+	var reply rerankReply
+	if err := json.Unmarshal(raw, &reply); err == nil {
+		return reply.Scores, nil
+	}
+
+	var scores []rerankScore
+	if err := json.Unmarshal(raw, &scores); err != nil {
+		return nil, fmt.Errorf("invalid rerank json: %w", err)
+	}
+	return scores, nil
+}
+
+var _ rag.Reranker = (*LLMReranker)(nil)
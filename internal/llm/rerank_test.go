@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+)
+
+// stubCompleter devolve sempre a mesma resposta e guarda o último prompt.
+type stubCompleter struct {
+	reply  string
+	err    error
+	prompt string
+}
+
+func (s *stubCompleter) Complete(_ context.Context, prompt string, jsonOutput bool) (string, error) {
+	if !jsonOutput {
+		return "", errors.New("reranker must ask for JSON")
+	}
+	s.prompt = prompt
+	return s.reply, s.err
+}
+
+func TestLLMReranker(t *testing.T) {
+	chunks := []rag.DocChunk{
+		{ID: 1, Title: "Visão geral", Content: "intro", Score: 0.7},
+		{ID: 2, Title: "Estorno", Content: "POST /refunds"},
+		{ID: 3, Title: "Captura", Content: "PUT /transactions"},
+	}
+
+	tests := []struct {
+		name   string
+		reply  string
+		ids    []int64
+		scores []float64
+	}{
+		{"object", `{"scores": [{"doc": 1, "score": 2}, {"doc": 2, "score": 9}, {"doc": 3, "score": 5}]}`, []int64{2, 3, 1}, []float64{0.9, 0.5, 0.2}},
+		{"array", `[{"doc": 3, "score": 10}]`, []int64{3, 1, 2}, []float64{1, 0, 0}},
+		{"code fence", "```json\n{\"scores\": [{\"doc\": 2, \"score\": 4}]}\n```", []int64{2, 1, 3}, []float64{0.4, 0, 0}},
+		{"out of range", `{"scores": [{"doc": 0, "score": 9}, {"doc": 4, "score": 9}, {"doc": 3, "score": 1}]}`, []int64{3, 1, 2}, []float64{0.1, 0, 0}},
+		{"empty", `{"scores": []}`, []int64{1, 2, 3}, []float64{0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubCompleter{reply: tt.reply}
+			out, err := NewLLMReranker(stub).Rerank(context.Background(), "como estornar?", chunks)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range out {
+				if out[i].ID != tt.ids[i] || out[i].Score != tt.scores[i] {
+					t.Fatalf("out[%d] = id %d score %v, want id %d score %v", i, out[i].ID, out[i].Score, tt.ids[i], tt.scores[i])
+				}
+			}
+			if !strings.Contains(stub.prompt, "como estornar?") || !strings.Contains(stub.prompt, "[3] title=Captura") {
+				t.Errorf("prompt = %q", stub.prompt)
+			}
+		})
+	}
+	if chunks[0].Score != 0.7 {
+		t.Errorf("input slice was modified: %+v", chunks[0])
+	}
+}
+
+func TestLLMRerankerErrors(t *testing.T) {
+	chunks := []rag.DocChunk{{ID: 1, Content: "a"}}
+
+	if _, err := NewLLMReranker(&stubCompleter{reply: "doc 1 is great"}).Rerank(context.Background(), "q", chunks); err == nil || !strings.Contains(err.Error(), "invalid rerank json") {
+		t.Errorf("invalid json: err = %v", err)
+	}
+	if _, err := NewLLMReranker(&stubCompleter{err: errors.New("boom")}).Rerank(context.Background(), "q", chunks); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("completer error: err = %v", err)
+	}
+
+	// sem candidatos não chama o modelo
+	stub := &stubCompleter{err: errors.New("should not be called")}
+	if out, err := NewLLMReranker(stub).Rerank(context.Background(), "q", nil); err != nil || len(out) != 0 {
+		t.Errorf("no chunks: out = %v, err = %v", out, err)
+	}
+}
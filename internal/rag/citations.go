@@ -0,0 +1,152 @@
+package rag
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// citationRe casa as marcações de citação: [1], [2, 3].
+var citationRe = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// codeRe casa blocos e trechos de código, onde [1, 2] é um array e não citação.
+var codeRe = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+
+// citationGroup são marcações consecutivas ("[1][3]") logo depois de uma afirmação.
+type citationGroup struct {
+	start, end int // posição (bytes) da marcação no texto limpo
+	refs       []int
+}
+
+// applyCitations valida as marcações [n] da resposta contra as fontes enviadas ao modelo:
+// números que não correspondem a nenhuma fonte são removidos do texto, marcações vizinhas
+// viram uma citação só e cada citação aponta o trecho da resposta que ela sustenta
+// (da fronteira de frase anterior até a marcação).
+// Devolve o texto limpo, as citações e as fontes citadas; se o modelo não citou nada,
+// todas as fontes são mantidas (não dá para saber quais ele usou).
+func applyCitations(answer string, sources []SourceRef) (string, []Citation, []SourceRef) {
+	code := codeRe.FindAllStringIndex(answer, -1)
+	inCode := func(pos int) bool {
+		for _, c := range code {
+			if pos >= c[0] && pos < c[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var out strings.Builder
+	var groups []citationGroup
+	last := 0
+
+	for _, m := range citationRe.FindAllStringSubmatchIndex(answer, -1) {
+		if inCode(m[0]) {
+			continue
+		}
+		out.WriteString(answer[last:m[0]])
+		last = m[1]
+
+		var refs []int
+		for _, part := range strings.Split(answer[m[2]:m[3]], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err == nil && n >= 1 && n <= len(sources) {
+				refs = append(refs, n)
+			}
+		}
+
+		if len(refs) == 0 {
+			// marcação inválida: some junto com o espaço que a antecedia
+			trimmed := strings.TrimRight(out.String(), " ")
+			out.Reset()
+			out.WriteString(trimmed)
+			continue
+		}
+
+		pos := out.Len()
+		for _, n := range refs {
+			out.WriteString("[" + strconv.Itoa(n) + "]")
+		}
+
+		// "[1][2]" ou "[1] [2]": junta com a marcação anterior
+		if k := len(groups) - 1; k >= 0 && strings.TrimSpace(out.String()[groups[k].end:pos]) == "" {
+			groups[k].end = out.Len()
+			groups[k].refs = appendUnique(groups[k].refs, refs...)
+			continue
+		}
+		groups = append(groups, citationGroup{start: pos, end: out.Len(), refs: appendUnique(nil, refs...)})
+	}
+	out.WriteString(answer[last:])
+	text := out.String()
+
+	citations := make([]Citation, 0, len(groups))
+	cited := make(map[int]bool)
+	prevEnd := 0
+	for _, g := range groups {
+		start := claimStart(text, prevEnd, g.start)
+		end := g.start
+		for end > start && text[end-1] == ' ' {
+			end--
+		}
+		prevEnd = g.end
+
+		c := Citation{
+			Text:  text[start:end],
+			Start: utf8.RuneCountInString(text[:start]),
+			End:   utf8.RuneCountInString(text[:end]),
+			Refs:  g.refs,
+		}
+		for _, n := range g.refs {
+			c.ChunkIDs = append(c.ChunkIDs, sources[n-1].ChunkID)
+			cited[n] = true
+		}
+		citations = append(citations, c)
+	}
+
+	if len(cited) == 0 {
+		return text, citations, sources
+	}
+	kept := make([]SourceRef, 0, len(cited))
+	for _, s := range sources {
+		if cited[s.Ref] {
+			kept = append(kept, s)
+		}
+	}
+	return text, citations, kept
+}
+
+// claimStart acha o começo da afirmação que termina em end: o fim da frase anterior
+// (". ", "! ", "? ", quebra de linha) ou a marcação anterior, o que vier por último.
+func claimStart(text string, from, end int) int {
+	start := from
+	seg := text[from:end]
+	if i := strings.LastIndexByte(seg, '\n'); i >= 0 {
+		start = from + i + 1
+	}
+	for _, sep := range []string{". ", "! ", "? "} {
+		if i := strings.LastIndex(seg, sep); i >= 0 && from+i+len(sep) > start {
+			start = from + i + len(sep)
+		}
+	}
+	// pula pontuação e marcadores de lista que sobram no começo
+	for start < end && strings.ContainsRune(" .,;:!?-*\t", rune(text[start])) {
+		start++
+	}
+	return start
+}
+
+func appendUnique(dst []int, vals ...int) []int {
+	for _, v := range vals {
+		found := false
+		for _, d := range dst {
+			if d == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dst = append(dst, v)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,61 @@
+package rag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyCitations(t *testing.T) {
+	sources := []SourceRef{
+		{Ref: 1, ChunkID: 10},
+		{Ref: 2, ChunkID: 20},
+		{Ref: 3, ChunkID: 30},
+	}
+
+	answer := "A captura usa POST /v1/capture [2]. O estorno é parcial [1, 3] [3]. Prazo de 90 dias [7].\n" +
+		"Exemplo: `{\"items\": [1, 2]}`"
+
+	text, citations, kept := applyCitations(answer, sources)
+
+	wantText := "A captura usa POST /v1/capture [2]. O estorno é parcial [1][3] [3]. Prazo de 90 dias.\n" +
+		"Exemplo: `{\"items\": [1, 2]}`"
+	if text != wantText {
+		t.Fatalf("text =\n%q\nwant\n%q", text, wantText)
+	}
+
+	if len(citations) != 2 {
+		t.Fatalf("citations = %+v, want 2", citations)
+	}
+	if c := citations[0]; c.Text != "A captura usa POST /v1/capture" || !reflect.DeepEqual(c.ChunkIDs, []int64{20}) {
+		t.Errorf("citation 0 = %+v", c)
+	}
+	if c := citations[1]; c.Text != "O estorno é parcial" || !reflect.DeepEqual(c.Refs, []int{1, 3}) {
+		t.Errorf("citation 1 = %+v", c)
+	}
+	if got := []rune(text)[citations[1].Start:citations[1].End]; string(got) != citations[1].Text {
+		t.Errorf("span %d:%d = %q, want %q", citations[1].Start, citations[1].End, string(got), citations[1].Text)
+	}
+
+	var refs []int
+	for _, s := range kept {
+		refs = append(refs, s.Ref)
+	}
+	if !reflect.DeepEqual(refs, []int{1, 2, 3}) {
+		t.Errorf("kept refs = %v", refs)
+	}
+}
+
+func TestApplyCitationsDropsUncitedSources(t *testing.T) {
+	sources := []SourceRef{{Ref: 1, ChunkID: 10}, {Ref: 2, ChunkID: 20}}
+
+	_, _, kept := applyCitations("Só a segunda fonte fala disso [2].", sources)
+	if len(kept) != 1 || kept[0].ChunkID != 20 {
+		t.Fatalf("kept = %+v, want only chunk 20", kept)
+	}
+
+	// sem nenhuma citação válida: mantém todas
+	_, citations, kept := applyCitations("Resposta sem marcações.", sources)
+	if len(citations) != 0 || len(kept) != 2 {
+		t.Fatalf("citations = %+v, kept = %+v", citations, kept)
+	}
+}
@@ -0,0 +1,86 @@
+package rag
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Modo comparação: a mesma pergunta buscada em vários providers e respondida lado a lado.
+
+// comparisonMaxChunks é o total de trechos da comparação, dividido entre os providers
+// (o mesmo limite de trechos que os prompts enviam ao modelo).
+const comparisonMaxChunks = MaxPromptChunks
+
+// comparisonRe reconhece perguntas comparativas; nelas um apelido ambíguo citado junto
+// com outro gateway ("entre rede e entrepay") conta como gateway.
+var comparisonRe = regexp.MustCompile(`(?i)\b(?:compar\w*|difer\w*|differ\w*|versus|vs\.?|between)\b|\bentre\b.+\be\b`)
+
+// resolveProviders decide em quais providers buscar: a lista de AskRequest.Providers
+// ("all" = todos os habilitados), o AskRequest.Provider, ou os gateways citados na pergunta.
+// Vazio quando nada identifica o provider; a detecção traz então os apelidos ambíguos
+// encontrados, para a votação por busca vetorial.
+func resolveProviders(providers []ProviderInfo, req AskRequest, question string) ([]Provider, *ProviderDetection, error) {
+	explicit := &ProviderDetection{Method: DetectExplicit, Confidence: 1}
+	var out []Provider
+	add := func(p Provider) {
+		for _, ex := range out {
+			if ex == p {
+				return
+			}
+		}
+		out = append(out, p)
+	}
+
+	if len(req.Providers) > 0 {
+		for _, name := range req.Providers {
+			if name == ProviderAll {
+				for _, p := range providers {
+					add(p.Slug)
+				}
+				continue
+			}
+			info := LookupProvider(providers, string(name))
+			if info == nil {
+				return nil, nil, fmt.Errorf("%w %q (use %s)", ErrUnknownProvider, name, providerHint(providers))
+			}
+			add(info.Slug)
+		}
+		return out, explicit.with(out), nil
+	}
+
+	if req.Provider != nil && *req.Provider != "" {
+		p, err := resolveProvider(providers, req.Provider, question)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []Provider{p}, explicit.with([]Provider{p}), nil
+	}
+
+	strong, weak, all := matchProviders(providers, question)
+	det := &ProviderDetection{Method: DetectKeyword, Confidence: 1}
+	if len(strong) == 0 {
+		det.weak = weak
+		return nil, det, nil
+	}
+	if len(weak) > 0 && comparisonRe.MatchString(question) {
+		return all, det.with(all), nil
+	}
+	return strong, det.with(strong), nil
+}
+
+// comparing devolve os providers comparados (nil fora do modo comparação).
+func (r *retrieval) comparing() []Provider {
+	if len(r.providers) > 1 {
+		return r.providers
+	}
+	return nil
+}
+
+// groupSources agrupa as fontes por provider (AskResponse.SourcesByProvider).
+func groupSources(sources []SourceRef) map[Provider][]SourceRef {
+	out := make(map[Provider][]SourceRef)
+	for _, s := range sources {
+		out[s.Provider] = append(out[s.Provider], s)
+	}
+	return out
+}
@@ -0,0 +1,109 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ConversationStore guarda o histórico das conversas multi-turn do /ask.
+type ConversationStore interface {
+	CreateConversation(ctx context.Context, provider Provider) (string, error)
+	// GetConversation devolve nil (sem erro) quando a conversa não existe.
+	GetConversation(ctx context.Context, id string) (*Conversation, error)
+	// ListMessages devolve as últimas limit mensagens, em ordem cronológica.
+	ListMessages(ctx context.Context, id string, limit int) ([]ConversationMessage, error)
+	AppendMessages(ctx context.Context, id string, msgs ...ConversationMessage) error
+}
+
+var uuidRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// historyLimit: quantas mensagens anteriores entram no contexto (condensação + geração).
+const historyLimit = 10
+
+func (r *PgRepository) CreateConversation(ctx context.Context, provider Provider) (string, error) {
+	var id string
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO conversation (provider)
+		VALUES ($1)
+		RETURNING id::text
+	`, provider).Scan(&id)
+	return id, err
+}
+
+func (r *PgRepository) GetConversation(ctx context.Context, id string) (*Conversation, error) {
+	// id vem do cliente: formato inválido é "não existe", não erro de SQL
+	if !uuidRe.MatchString(id) {
+		return nil, nil
+	}
+
+	var c Conversation
+	err := r.db.QueryRow(ctx, `
+		SELECT id::text, COALESCE(provider, ''), created_at, updated_at
+		FROM conversation
+		WHERE id = $1::uuid
+	`, id).Scan(&c.ID, &c.Provider, &c.CreatedAt, &c.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *PgRepository) ListMessages(ctx context.Context, id string, limit int) ([]ConversationMessage, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT role, content, created_at
+		FROM (
+			SELECT id, role, content, created_at
+			FROM conversation_message
+			WHERE conversation_id = $1::uuid
+			ORDER BY id DESC
+			LIMIT $2
+		) last
+		ORDER BY id
+	`, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []ConversationMessage
+	for rows.Next() {
+		var m ConversationMessage
+		if err := rows.Scan(&m.Role, &m.Content, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
+
+func (r *PgRepository) AppendMessages(ctx context.Context, id string, msgs ...ConversationMessage) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	for _, m := range msgs {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO conversation_message (conversation_id, role, content)
+			VALUES ($1::uuid, $2, $3)
+		`, id, m.Role, m.Content); err != nil {
+			return fmt.Errorf("insert message: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE conversation SET updated_at = NOW() WHERE id = $1::uuid`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+var _ ConversationStore = (*PgRepository)(nil)
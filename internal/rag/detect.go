@@ -0,0 +1,144 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Detecção do provider quando a pergunta não cita nenhum gateway de forma inequívoca:
+// busca vetorial sem filtro de provider e votação pelos providers dos trechos mais próximos.
+
+// DetectionMethod diz como o provider da pergunta foi escolhido.
+type DetectionMethod string
+
+const (
+	DetectExplicit     DetectionMethod = "explicit"     // provider/providers do request
+	DetectKeyword      DetectionMethod = "keyword"      // apelido citado na pergunta
+	DetectConversation DetectionMethod = "conversation" // provider da conversa (follow-up)
+	DetectVector       DetectionMethod = "vector"       // votação pela busca vetorial
+)
+
+const (
+	// providerVoteK é quantos trechos (de todos os providers) votam.
+	providerVoteK = 10
+
+	// providerMinConfidence é a fatia mínima dos votos para o provider vencedor valer;
+	// abaixo disso a API pede para o usuário dizer o gateway.
+	providerMinConfidence = 0.6
+
+	// ambiguousAliasVote é o peso de um apelido ambíguo citado na pergunta ("rede"),
+	// como fração dos votos da busca: desempata, mas não vence uma busca clara.
+	ambiguousAliasVote = 0.5
+)
+
+// ProviderVote é a fatia dos votos de um provider (0..1).
+type ProviderVote struct {
+	Provider Provider `json:"provider"`
+	Score    float64  `json:"score"`
+}
+
+// ProviderDetection
+// Como o provider foi escolhido, devolvido no AskResponse/SearchResponse. Ambiguous indica
+// que a votação não foi conclusiva e a resposta é um pedido de esclarecimento.
+type ProviderDetection struct {
+	Providers  []Provider      `json:"providers,omitempty"`
+	Method     DetectionMethod `json:"method"`
+	Confidence float64         `json:"confidence"`
+	Candidates []ProviderVote  `json:"candidates,omitempty"`
+	Ambiguous  bool            `json:"ambiguous,omitempty"`
+
+	// weak: providers citados só por apelidos ambíguos (entram na votação)
+	weak []Provider
+}
+
+func (d *ProviderDetection) with(providers []Provider) *ProviderDetection {
+	d.Providers = providers
+	return d
+}
+
+// voteProvider escolhe o provider pelos trechos mais próximos de todos os providers
+// habilitados. Cada trecho vota com a sua similaridade; os apelidos ambíguos citados
+// na pergunta somam ambiguousAliasVote do total. Devolve nil se não há trecho nenhum.
+func (s *Service) voteProvider(ctx context.Context, providers []ProviderInfo, embedding []float32, weak []Provider) (*ProviderDetection, error) {
+	chunks, err := s.repo.SearchSimilarChunks(ctx, "", embedding, providerVoteK, SearchFilters{})
+	if err != nil {
+		return nil, fmt.Errorf("provider vote: %w", err)
+	}
+
+	enabled := make(map[Provider]bool, len(providers))
+	for _, p := range providers {
+		enabled[p.Slug] = true
+	}
+
+	scores := make(map[Provider]float64)
+	total := 0.0
+	for _, c := range chunks {
+		if !enabled[c.Provider] || c.Similarity <= 0 {
+			continue
+		}
+		scores[c.Provider] += c.Similarity
+		total += c.Similarity
+	}
+	if total == 0 && len(weak) == 0 {
+		return nil, nil
+	}
+
+	prior := ambiguousAliasVote * total
+	if total == 0 {
+		prior = 1
+	}
+	for _, p := range weak {
+		scores[p] += prior
+	}
+
+	sum := 0.0
+	for _, v := range scores {
+		sum += v
+	}
+	det := &ProviderDetection{Method: DetectVector}
+	for p, v := range scores {
+		det.Candidates = append(det.Candidates, ProviderVote{Provider: p, Score: v / sum})
+	}
+	sort.Slice(det.Candidates, func(a, b int) bool {
+		if det.Candidates[a].Score != det.Candidates[b].Score {
+			return det.Candidates[a].Score > det.Candidates[b].Score
+		}
+		return det.Candidates[a].Provider < det.Candidates[b].Provider
+	})
+
+	best := det.Candidates[0]
+	det.Confidence = best.Score
+	if best.Score >= providerMinConfidence {
+		det.Providers = []Provider{best.Provider}
+	} else {
+		det.Ambiguous = true
+	}
+	return det, nil
+}
+
+// clarificationAnswer é a resposta quando a votação não decide o provider.
+func clarificationAnswer(det *ProviderDetection, providers []ProviderInfo, lang string) string {
+	var names []string
+	for _, c := range det.Candidates {
+		name := string(c.Provider)
+		if info := LookupProvider(providers, name); info != nil {
+			name = info.DisplayName
+		}
+		names = append(names, fmt.Sprintf("%s (%.0f%%)", name, c.Score*100))
+	}
+	list := strings.Join(names, ", ")
+
+	switch lang {
+	case "en":
+		return "I couldn't tell which gateway this question is about (" + list + "). " +
+			"Please mention the gateway in the question or send the \"provider\" field."
+	case "es":
+		return "No pude identificar a qué gateway se refiere la pregunta (" + list + "). " +
+			"Menciona el gateway en la pregunta o envía el campo \"provider\"."
+	default:
+		return "Não consegui identificar de qual gateway é a pergunta (" + list + "). " +
+			"Cite o gateway na pergunta ou envie o campo \"provider\"."
+	}
+}
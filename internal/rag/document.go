@@ -0,0 +1,113 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DocumentRepository guarda os documentos de origem do import-doc,
+// para reimportar de forma idempotente.
+type DocumentRepository interface {
+	// GetDocument devolve nil (sem erro) quando o documento ainda não foi importado.
+	GetDocument(ctx context.Context, provider Provider, source string) (*Document, error)
+	// ReplaceDocument grava o documento e troca todos os chunks dele, atomicamente.
+	ReplaceDocument(ctx context.Context, doc *Document, chunks []DocChunk, embeddings [][]float32) (int64, error)
+	// PruneDocuments remove os documentos do provider sob sourcePrefix (diretório ou URL base,
+	// ver underPrefix) que não estão em keep (os chunks vão junto via ON DELETE CASCADE).
+	PruneDocuments(ctx context.Context, provider Provider, sourcePrefix string, keep []string) (int64, error)
+}
+
+func (r *PgRepository) GetDocument(ctx context.Context, provider Provider, source string) (*Document, error) {
+	var d Document
+	err := r.db.QueryRow(ctx, `
+		SELECT id, provider, source, content_hash, COALESCE(api_version, ''), imported_at
+		FROM document
+		WHERE provider = $1 AND source = $2
+	`, provider, source).Scan(
+		&d.ID,
+		&d.Provider,
+		&d.Source,
+		&d.ContentHash,
+		&d.APIVersion,
+		&d.ImportedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *PgRepository) ReplaceDocument(ctx context.Context, doc *Document, chunks []DocChunk, embeddings [][]float32) (int64, error) {
+	if len(chunks) != len(embeddings) {
+		return 0, fmt.Errorf("chunks/embeddings mismatch: %d != %d", len(chunks), len(embeddings))
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }() // no-op depois do Commit
+
+	var docID int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO document (provider, source, content_hash, api_version, imported_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (provider, source) DO UPDATE
+		SET content_hash = EXCLUDED.content_hash,
+		    api_version  = EXCLUDED.api_version,
+		    imported_at  = EXCLUDED.imported_at
+		RETURNING id
+	`, doc.Provider, doc.Source, doc.ContentHash, doc.APIVersion).Scan(&docID)
+	if err != nil {
+		return 0, fmt.Errorf("upsert document: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM doc_chunk WHERE document_id = $1`, docID); err != nil {
+		return 0, fmt.Errorf("delete old chunks: %w", err)
+	}
+
+	for i := range chunks {
+		if _, err := insertChunk(ctx, tx, &chunks[i], &docID, embeddings[i]); err != nil {
+			return 0, fmt.Errorf("insert chunk: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	doc.ID = docID
+	return docID, nil
+}
+
+func (r *PgRepository) PruneDocuments(ctx context.Context, provider Provider, sourcePrefix string, keep []string) (int64, error) {
+	if keep == nil {
+		keep = []string{}
+	}
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM document
+		WHERE provider = $1
+		  AND (source = $2 OR starts_with(source, $2 || '/'))
+		  AND NOT (source = ANY($3))
+	`, provider, strings.TrimRight(sourcePrefix, "/"), keep)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// underPrefix diz se source é o próprio sourcePrefix ou está dentro dele. A comparação é por
+// segmento: "docs" não pega "docs-old/x.md", nem ".../e-rede" pega ".../e-rede-v2/x".
+func underPrefix(source, sourcePrefix string) bool {
+	sourcePrefix = strings.TrimRight(sourcePrefix, "/")
+	return source == sourcePrefix || strings.HasPrefix(source, sourcePrefix+"/")
+}
+
+var _ DocumentRepository = (*PgRepository)(nil)
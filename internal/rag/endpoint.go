@@ -0,0 +1,513 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Catálogo de endpoints: método + path, descrição e parâmetros extraídos dos chunks
+// no import. Responde "qual endpoint faz X" sem depender do LLM e cita o chunk de origem.
+
+// EndpointParam é um parâmetro documentado de um endpoint.
+type EndpointParam struct {
+	Name        string `json:"name"`
+	In          string `json:"in,omitempty"` // path, query, header, body...
+	Type        string `json:"type,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Endpoint
+// Uma operação HTTP achada num chunk. Title, SourceURL e APIVersion vêm do chunk
+// (para citar); Score só é preenchido na busca por texto.
+type Endpoint struct {
+	ID          int64           `json:"id"`
+	Provider    Provider        `json:"provider"`
+	Method      string          `json:"method"`
+	Path        string          `json:"path"`
+	Description string          `json:"description,omitempty"`
+	Parameters  []EndpointParam `json:"parameters"`
+	ChunkID     int64           `json:"chunkId"`
+	Title       string          `json:"title"`
+	SourceURL   string          `json:"sourceUrl"`
+	APIVersion  string          `json:"apiVersion,omitempty"`
+	Score       float64         `json:"score,omitempty"`
+}
+
+// EndpointCatalog lê a tabela endpoint. A escrita acontece junto com a dos chunks
+// (DocChunk.Endpoints), e reimportar o documento troca os endpoints dele.
+type EndpointCatalog interface {
+	// ListEndpoints devolve os endpoints do provider, um por chunk onde aparecem,
+	// ordenados por path e método.
+	ListEndpoints(ctx context.Context, provider Provider) ([]Endpoint, error)
+}
+
+func (r *PgRepository) ListEndpoints(ctx context.Context, provider Provider) ([]Endpoint, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.id, e.provider, e.method, e.path, e.description, e.parameters,
+		       e.chunk_id, COALESCE(c.title, ''), COALESCE(c.source_url, ''), COALESCE(c.api_version, '')
+		FROM endpoint e
+		JOIN doc_chunk c ON c.id = e.chunk_id
+		WHERE e.provider = $1
+		ORDER BY e.path, e.method, e.id
+	`, provider)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Endpoint
+	for rows.Next() {
+		var e Endpoint
+		if err := rows.Scan(&e.ID, &e.Provider, &e.Method, &e.Path, &e.Description, &e.Parameters,
+			&e.ChunkID, &e.Title, &e.SourceURL, &e.APIVersion); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// insertEndpoints grava os endpoints do chunk (dentro da mesma transação do chunk).
+func insertEndpoints(ctx context.Context, db dbtx, c *DocChunk) error {
+	for _, e := range c.Endpoints {
+		params := e.Parameters
+		if params == nil {
+			params = []EndpointParam{}
+		}
+		if _, err := db.Exec(ctx, `
+			INSERT INTO endpoint (provider, method, path, description, parameters, chunk_id)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, c.Provider, e.Method, e.Path, e.Description, params, c.ID); err != nil {
+			return fmt.Errorf("insert endpoint %s %s: %w", e.Method, e.Path, err)
+		}
+	}
+	return nil
+}
+
+var _ EndpointCatalog = (*PgRepository)(nil)
+
+// askEndpointMatches é quantos endpoints do catálogo vão no AskResponse.
+const askEndpointMatches = 3
+
+var (
+	// método + path, com ou sem base URL (https://host/... ou {{baseUrl}}/... do Postman)
+	endpointRe = regexp.MustCompile(`\b(GET|POST|PUT|PATCH|DELETE)\s+(?:https?://[^\s/]+|\{\{[^}\s]+\}\})?(/[^\s?#"'\x60<>|)]+)`)
+
+	// "- campo (tipo, obrigatório): descrição", como o importador OpenAPI descreve schemas
+	schemaFieldRe = regexp.MustCompile(`^- ([\w.\-\[\]]+) \(([^,)]*)((?:, [^)]*)?)\)(?:: (.*))?$`)
+
+	paramNameRe = regexp.MustCompile(`^[\w.\-\[\]]+$`)
+
+	// pergunta que procura um endpoint ("qual a rota para estornar?")
+	endpointQuestionRe = regexp.MustCompile(`(?i)(?:^|[^\p{L}])(?:endpoints?|rotas?|paths?|urls?|chamadas?|requisiç(?:ão|ões)|requests?)(?:$|[^\p{L}])`)
+)
+
+// ExtractEndpoints acha as operações HTTP citadas num chunk. Parâmetros (tabelas
+// Markdown e listas de campos) só são atribuídos quando o chunk fala de um endpoint só;
+// com vários, não dá para saber de qual é cada tabela. Sem endpoint no conteúdo, vale
+// o do título (continuação de uma operação OpenAPI quebrada em mais de um chunk).
+func ExtractEndpoints(title, content string) []Endpoint {
+	lines := strings.Split(content, "\n")
+
+	type found struct {
+		Endpoint
+		line string
+	}
+	var eps []found
+	for _, line := range lines {
+		for _, m := range endpointRe.FindAllStringSubmatch(line, -1) {
+			eps = append(eps, found{Endpoint: Endpoint{Method: m[1], Path: trimPath(m[2])}, line: line})
+		}
+	}
+	if len(eps) == 0 {
+		for _, m := range endpointRe.FindAllStringSubmatch(title, -1) {
+			eps = append(eps, found{Endpoint: Endpoint{Method: m[1], Path: trimPath(m[2])}})
+		}
+	}
+
+	// mesmo método com path repetido ou com prefixo da base URL ("URL: GET https://api/erede/v1/x")
+	// é o mesmo endpoint: fica a primeira ocorrência
+	var out []Endpoint
+	var descLines []string
+	for _, e := range eps {
+		if e.Path == "/" || e.Path == "" {
+			continue
+		}
+		dup := false
+		for _, o := range out {
+			a, b := normalizePath(o.Path), normalizePath(e.Path)
+			if o.Method == e.Method && (strings.HasSuffix(a, b) || strings.HasSuffix(b, a)) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			out = append(out, e.Endpoint)
+			descLines = append(descLines, e.line)
+		}
+	}
+
+	for i := range out {
+		out[i].Description = lineDescription(descLines[i])
+		out[i].Parameters = []EndpointParam{}
+	}
+	if len(out) == 1 {
+		if out[0].Description == "" {
+			out[0].Description = contentDescription(title, lines)
+		}
+		out[0].Parameters = extractParams(lines)
+	}
+	return out
+}
+
+func trimPath(p string) string {
+	return strings.TrimRight(p, ".,;:")
+}
+
+// lineDescription usa a própria frase onde o endpoint aparece, quando ela diz mais
+// que "POST /v1/x" e não cita outro endpoint junto.
+func lineDescription(line string) string {
+	line = strings.Join(strings.Fields(line), " ")
+	if len(endpointRe.FindAllStringIndex(line, 2)) > 1 {
+		return ""
+	}
+	rest := endpointRe.ReplaceAllString(line, "")
+	rest = strings.Trim(rest, " -—:|`*#")
+	if len(strings.Fields(rest)) < 2 {
+		return ""
+	}
+	return clip(line, 200)
+}
+
+// contentDescription procura o resumo do endpoint: linha "Resumo:"/"Request:" dos
+// importadores OpenAPI e Postman, senão o primeiro parágrafo de texto, senão o título.
+func contentDescription(title string, lines []string) string {
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		for _, label := range []string{"Resumo:", "Summary:", "Request:"} {
+			if strings.HasPrefix(l, label) {
+				return clip(strings.TrimSpace(strings.TrimPrefix(l, label)), 200)
+			}
+		}
+	}
+
+	inCode := false
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode || l == "" || strings.HasPrefix(l, "|") || strings.HasPrefix(l, "-") ||
+			strings.HasPrefix(l, "#") || strings.HasSuffix(l, ":") || endpointRe.MatchString(l) {
+			continue
+		}
+		if i := strings.Index(l, ":"); i > 0 && i < 20 && !strings.Contains(l[:i], " ") {
+			continue // "Tags: x", "Pasta: y"
+		}
+		return clip(l, 200)
+	}
+
+	if i := strings.LastIndex(title, " > "); i >= 0 {
+		title = title[i+3:]
+	}
+	if endpointRe.MatchString(title) {
+		return ""
+	}
+	return strings.TrimSpace(title)
+}
+
+// extractParams lê as tabelas Markdown com coluna de nome (nome/name/campo/header...)
+// e as listas de campos de primeiro nível. O "in" vem da coluna "em"/"in" ou do rótulo
+// antes da tabela ("Headers:", "Query params:", "Request body:").
+func extractParams(lines []string) []EndpointParam {
+	params := []EndpointParam{}
+	seen := make(map[string]bool)
+	add := func(p EndpointParam) {
+		key := p.In + "|" + p.Name
+		if p.Name == "" || !paramNameRe.MatchString(p.Name) || seen[key] {
+			return
+		}
+		seen[key] = true
+		params = append(params, p)
+	}
+
+	label := ""
+	var cols map[string]int
+	for _, raw := range lines {
+		l := strings.TrimSpace(raw)
+
+		if !strings.HasPrefix(l, "|") {
+			cols = nil
+			// só campos de primeiro nível (sem indentação) do corpo
+			if m := schemaFieldRe.FindStringSubmatch(raw); m != nil {
+				if strings.Contains(label, "request body") {
+					add(EndpointParam{
+						Name:        m[1],
+						In:          "body",
+						Type:        m[2],
+						Required:    strings.Contains(m[3], "obrigatório"),
+						Description: m[4],
+					})
+				}
+				continue
+			}
+			if strings.HasSuffix(l, ":") {
+				label = strings.ToLower(l)
+			}
+			continue
+		}
+
+		cells := tableCells(l)
+		if cols == nil {
+			cols = paramColumns(cells)
+			if cols == nil {
+				cols = map[string]int{} // tabela sem coluna de nome: ignora até acabar
+			}
+			continue
+		}
+		if _, ok := cols["name"]; !ok || isSeparatorRow(cells) {
+			continue
+		}
+
+		cell := func(key string) string {
+			if i, ok := cols[key]; ok && i < len(cells) {
+				return strings.Trim(cells[i], "`* ")
+			}
+			return ""
+		}
+		in := strings.ToLower(cell("in"))
+		if in == "" {
+			in = labelLocation(label)
+		}
+		add(EndpointParam{
+			Name:        cell("name"),
+			In:          in,
+			Type:        cell("type"),
+			Required:    isYes(cell("required")),
+			Description: cell("description"),
+		})
+	}
+	return params
+}
+
+var paramHeaders = map[string][]string{
+	"name":        {"nome", "name", "parâmetro", "parametro", "parameter", "campo", "field", "header", "propriedade", "property"},
+	"in":          {"em", "in", "local", "location"},
+	"type":        {"tipo", "type"},
+	"required":    {"obrigatório", "obrigatorio", "required", "obrigatoriedade"},
+	"description": {"descrição", "descricao", "description"},
+}
+
+// paramColumns mapeia o cabeçalho da tabela; nil se não há coluna de nome.
+func paramColumns(cells []string) map[string]int {
+	cols := make(map[string]int)
+	for i, c := range cells {
+		c = strings.ToLower(strings.Trim(c, "`* "))
+		for key, names := range paramHeaders {
+			if _, ok := cols[key]; !ok && containsString(names, c) {
+				cols[key] = i
+			}
+		}
+	}
+	if _, ok := cols["name"]; !ok {
+		return nil
+	}
+	return cols
+}
+
+func tableCells(line string) []string {
+	line = strings.Trim(strings.TrimSpace(line), "|")
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+func isSeparatorRow(cells []string) bool {
+	for _, c := range cells {
+		if strings.Trim(c, "-: ") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func labelLocation(label string) string {
+	switch {
+	case strings.Contains(label, "header"):
+		return "header"
+	case strings.Contains(label, "query"):
+		return "query"
+	case strings.Contains(label, "body") || strings.Contains(label, "corpo"):
+		return "body"
+	case strings.Contains(label, "path"):
+		return "path"
+	}
+	return ""
+}
+
+func isYes(v string) bool {
+	switch strings.ToLower(v) {
+	case "sim", "s", "yes", "y", "true", "obrigatório", "obrigatorio", "required":
+		return true
+	}
+	return false
+}
+
+func clip(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(r[:n])) + "…"
+}
+
+// mergeEndpoints junta as ocorrências do mesmo endpoint em chunks diferentes (visão
+// geral + página do endpoint, OpenAPI + Postman): fica a que tem mais parâmetros e,
+// no empate, a descrição mais longa.
+func mergeEndpoints(all []Endpoint) []Endpoint {
+	index := make(map[string]int)
+	var out []Endpoint
+	for _, e := range all {
+		key := e.Method + " " + normalizePath(e.Path)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(out)
+			out = append(out, e)
+			continue
+		}
+		cur := out[i]
+		if len(e.Parameters) > len(cur.Parameters) ||
+			(len(e.Parameters) == len(cur.Parameters) && len(e.Description) > len(cur.Description)) {
+			out[i] = e
+		}
+	}
+	return out
+}
+
+// verbos da pergunta que apontam para um método HTTP
+var methodHints = map[string]string{
+	"criar": "POST", "cria": "POST", "create": "POST", "cadastrar": "POST", "enviar": "POST", "gerar": "POST",
+	"consultar": "GET", "consulta": "GET", "buscar": "GET", "listar": "GET", "obter": "GET", "get": "GET", "list": "GET",
+	"atualizar": "PUT", "alterar": "PUT", "update": "PUT",
+	"excluir": "DELETE", "remover": "DELETE", "deletar": "DELETE", "delete": "DELETE",
+}
+
+// palavras que só dizem "estou procurando um endpoint"
+var endpointWords = map[string]bool{
+	"endpoint": true, "endpoints": true, "rota": true, "rotas": true, "path": true, "paths": true,
+	"url": true, "urls": true, "api": true, "chamada": true, "chamar": true, "request": true,
+	"requisição": true, "usar": true, "uso": true, "devo": true, "usa": true,
+}
+
+// rankEndpoints pontua cada endpoint pela pergunta: termos que aparecem no path,
+// descrição, título do chunk ou nomes de parâmetros; tags em comum (a mesma DetectTags
+// do importador, que junta "estorno" e "refunds") valem 2; verbo compatível com o método
+// vale 1. Só voltam os endpoints com pontuação, do maior para o menor.
+func rankEndpoints(endpoints []Endpoint, query string) []Endpoint {
+	var terms []string
+	method := ""
+	for _, t := range questionTerms(query) {
+		if m, ok := methodHints[t]; ok && method == "" {
+			method = m
+		}
+		if !endpointWords[t] {
+			terms = append(terms, t)
+		}
+	}
+	queryTags := DetectTags(query)
+
+	var out []Endpoint
+	for _, e := range endpoints {
+		var text strings.Builder
+		text.WriteString(e.Path + " " + e.Description + " " + e.Title)
+		for _, p := range e.Parameters {
+			text.WriteString(" " + p.Name)
+		}
+		words := tokenSet(text.String())
+
+		score := 0.0
+		for _, t := range terms {
+			if words[t] {
+				score++
+			}
+		}
+		for _, t := range DetectTags(text.String()) {
+			if t != "transaction" && containsString(queryTags, t) {
+				score += 2
+			}
+		}
+		if score == 0 {
+			continue
+		}
+		if method != "" && (e.Method == method || method == "PUT" && e.Method == "PATCH") {
+			score++
+		}
+		e.Score = score
+		out = append(out, e)
+	}
+
+	sort.SliceStable(out, func(a, b int) bool { return out[a].Score > out[b].Score })
+	return out
+}
+
+// Endpoints lista o catálogo do provider (slug ou apelido), sem repetições. Com method,
+// só os desse método; com query, só os que casam com a pergunta, do mais para o menos relevante.
+func (s *Service) Endpoints(ctx context.Context, provider Provider, query, method string) ([]Endpoint, error) {
+	if s.endpoints == nil {
+		return nil, errors.New("endpoint catalog is not configured")
+	}
+
+	info, err := s.providerInfo(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := s.endpoints.ListEndpoints(ctx, info.Slug)
+	if err != nil {
+		return nil, err
+	}
+	list = mergeEndpoints(list)
+
+	if method = strings.ToUpper(strings.TrimSpace(method)); method != "" {
+		filtered := list[:0]
+		for _, e := range list {
+			if e.Method == method {
+				filtered = append(filtered, e)
+			}
+		}
+		list = filtered
+	}
+	if strings.TrimSpace(query) != "" {
+		list = rankEndpoints(list, query)
+	}
+	if list == nil {
+		list = []Endpoint{}
+	}
+	return list, nil
+}
+
+// matchEndpoints é a consulta ao catálogo feita pelo Ask quando a pergunta procura um
+// endpoint. Erro no catálogo não derruba a resposta.
+func (s *Service) matchEndpoints(ctx context.Context, provider Provider, question string) []Endpoint {
+	if s.endpoints == nil || provider == "" || !endpointQuestionRe.MatchString(question) {
+		return nil
+	}
+	matches, err := s.Endpoints(ctx, provider, question, "")
+	if err != nil {
+		log.Printf("endpoint catalog: %v", err)
+		return nil
+	}
+	if len(matches) > askEndpointMatches {
+		matches = matches[:askEndpointMatches]
+	}
+	return matches
+}
@@ -0,0 +1,95 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExtractEndpoints(t *testing.T) {
+	// chunk no formato do importador OpenAPI
+	openapi := "POST /v1/transactions/{tid}/refunds\n" +
+		"URL: POST https://api.userede.com.br/erede/v1/transactions/{tid}/refunds\n" +
+		"Resumo: Estorna uma transação\n\n" +
+		"Parâmetros:\n| nome | em | tipo | obrigatório | descrição |\n| --- | --- | --- | --- | --- |\n" +
+		"| tid | path | string | sim | id da transação |\n\n" +
+		"Request body (application/json):\n- amount (integer, obrigatório): valor em centavos\n  - nested (string)\n- urls (array)"
+
+	eps := ExtractEndpoints("API > Refunds > POST /v1/transactions/{tid}/refunds", openapi)
+	if len(eps) != 1 {
+		t.Fatalf("endpoints = %+v, want 1 (URL line is the same endpoint)", eps)
+	}
+	e := eps[0]
+	if e.Method != "POST" || e.Path != "/v1/transactions/{tid}/refunds" || e.Description != "Estorna uma transação" {
+		t.Errorf("endpoint = %+v", e)
+	}
+	want := []EndpointParam{
+		{Name: "tid", In: "path", Type: "string", Required: true, Description: "id da transação"},
+		{Name: "amount", In: "body", Type: "integer", Required: true, Description: "valor em centavos"},
+		{Name: "urls", In: "body", Type: "array"},
+	}
+	if !reflect.DeepEqual(e.Parameters, want) {
+		t.Errorf("parameters = %+v, want %+v", e.Parameters, want)
+	}
+
+	// continuação da mesma operação: o endpoint vem do título
+	eps = ExtractEndpoints("API > GET /v1/transactions/{tid}", "Headers:\n| header | valor | descrição |\n| --- | --- | --- |\n| Authorization | Bearer x | token |")
+	if len(eps) != 1 || eps[0].Path != "/v1/transactions/{tid}" || len(eps[0].Parameters) != 1 || eps[0].Parameters[0].In != "header" {
+		t.Errorf("from title: %+v", eps)
+	}
+
+	// texto corrido com dois endpoints: a frase vira descrição e não há parâmetros
+	eps = ExtractEndpoints("Guia", "Para capturar use PUT /v1/transactions/{tid}.\nO cancelamento é via DELETE /charges/{id} informando o motivo.\n| campo | tipo |\n| --- | --- |\n| reason | string |")
+	if len(eps) != 2 || eps[1].Method != "DELETE" || eps[1].Path != "/charges/{id}" || len(eps[1].Parameters) != 0 {
+		t.Fatalf("prose: %+v", eps)
+	}
+	if eps[0].Description != "Para capturar use PUT /v1/transactions/{tid}." {
+		t.Errorf("description = %q", eps[0].Description)
+	}
+}
+
+func TestServiceEndpoints(t *testing.T) {
+	repo, err := NewMemoryRepository("", MetricCosine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := []DocChunk{
+		{Provider: ProviderRede, Title: "Visão geral", Content: "Estorno: POST /v1/transactions/{tid}/refunds. Consulta: GET /v1/transactions/{tid}."},
+		{Provider: ProviderRede, Title: "Estorno", Content: "POST /v1/transactions/{tid}/refunds\nResumo: Estorna uma transação\n| nome | em |\n| --- | --- |\n| tid | path |"},
+		{Provider: ProviderEntrepay, Title: "Cobranças", Content: "DELETE /charges/{id}"},
+	}
+	for i := range chunks {
+		chunks[i].Endpoints = ExtractEndpoints(chunks[i].Title, chunks[i].Content)
+		if _, err := repo.InsertChunk(context.Background(), &chunks[i], []float32{1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc := NewService(repo, nil, nil, WithProviders(repo), WithEndpoints(repo))
+
+	// o refund aparece em dois chunks: fica o que tem parâmetros
+	all, err := svc.Endpoints(context.Background(), "e-rede", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0].Method != "GET" || all[1].ChunkID != chunks[1].ID || len(all[1].Parameters) != 1 {
+		t.Fatalf("endpoints = %+v", all)
+	}
+
+	found, err := svc.Endpoints(context.Background(), ProviderRede, "qual endpoint faz o estorno?", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].Path != "/v1/transactions/{tid}/refunds" || found[0].Score == 0 {
+		t.Fatalf("search = %+v", found)
+	}
+
+	if got := svc.matchEndpoints(context.Background(), ProviderRede, "como funciona o estorno?"); got != nil {
+		t.Errorf("question without endpoint words matched %+v", got)
+	}
+
+	if _, err := svc.Endpoints(context.Background(), "stone", "", ""); !errors.Is(err, ErrUnknownProvider) {
+		t.Fatalf("err = %v, want ErrUnknownProvider", err)
+	}
+}
@@ -0,0 +1,324 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// Tabela de códigos de erro/retorno: o importador lê as tabelas dos chunks de
+// section_type errors e grava um registro por código. "O que é o código 58 na e-Rede?"
+// sai direto da tabela, sem LLM, citando o chunk.
+
+// ErrorCode
+// Um código de erro/retorno documentado. Category e Retryable vêm da tabela da doc quando
+// ela tem essas colunas; senão são estimados pela mensagem (Retryable nil = sem indicação).
+type ErrorCode struct {
+	ID         int64    `json:"id"`
+	Provider   Provider `json:"provider"`
+	Code       string   `json:"code"`
+	Message    string   `json:"message"`
+	Category   string   `json:"category,omitempty"` // fraud, funds, card, auth, system, validation, issuer
+	Retryable  *bool    `json:"retryable,omitempty"`
+	ChunkID    int64    `json:"chunkId"`
+	Title      string   `json:"title"`
+	SourceURL  string   `json:"sourceUrl"`
+	APIVersion string   `json:"apiVersion,omitempty"`
+}
+
+// ErrorCodeCatalog lê a tabela provider_error_code. Como no catálogo de endpoints, a
+// escrita vai junto com o chunk (DocChunk.ErrorCodes).
+type ErrorCodeCatalog interface {
+	// FindErrorCode devolve as ocorrências do código no provider (uma por chunk).
+	FindErrorCode(ctx context.Context, provider Provider, code string) ([]ErrorCode, error)
+}
+
+func (r *PgRepository) FindErrorCode(ctx context.Context, provider Provider, code string) ([]ErrorCode, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.id, e.provider, e.code, e.message, e.category, e.retryable,
+		       e.chunk_id, COALESCE(c.title, ''), COALESCE(c.source_url, ''), COALESCE(c.api_version, '')
+		FROM provider_error_code e
+		JOIN doc_chunk c ON c.id = e.chunk_id
+		WHERE e.provider = $1 AND e.code = $2
+		ORDER BY e.id
+	`, provider, code)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ErrorCode
+	for rows.Next() {
+		var e ErrorCode
+		if err := rows.Scan(&e.ID, &e.Provider, &e.Code, &e.Message, &e.Category, &e.Retryable,
+			&e.ChunkID, &e.Title, &e.SourceURL, &e.APIVersion); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// insertErrorCodes grava os códigos do chunk (dentro da mesma transação do chunk).
+func insertErrorCodes(ctx context.Context, db dbtx, c *DocChunk) error {
+	for _, e := range c.ErrorCodes {
+		if _, err := db.Exec(ctx, `
+			INSERT INTO provider_error_code (provider, code, message, category, retryable, chunk_id)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, c.Provider, e.Code, e.Message, e.Category, e.Retryable, c.ID); err != nil {
+			return fmt.Errorf("insert error code %s: %w", e.Code, err)
+		}
+	}
+	return nil
+}
+
+var _ ErrorCodeCatalog = (*PgRepository)(nil)
+
+var (
+	// "58: mensagem", "Código 58 - mensagem", "- AC01 – mensagem", "58 mensagem"
+	errorLineRe = regexp.MustCompile(`^(?:[-*•]\s*)?(?:(?i:c[oó]digo|code|erro|error|retorno)\s+)?(\d{2,4}|[A-Z]{1,4}\d{1,4})\s*(?:[:=\-–—)]\s*|\s+)(.*\pL.*)$`)
+
+	retryNoRe  = regexp.MustCompile(`(?i)n[aã]o\s+(?:tente|retente|refa[cç]a)|do not (?:retry|try)|don't retry`)
+	retryYesRe = regexp.MustCompile(`(?i)tente novamente|tentar novamente|try again|retry|reenvie`)
+)
+
+var errorCodeHeaders = map[string][]string{
+	"code":      {"código", "codigo", "code", "returncode", "código de retorno", "codigo de retorno", "return code", "retorno", "erro", "error", "status"},
+	"message":   {"mensagem", "message", "returnmessage", "descrição", "descricao", "description", "significado", "motivo", "reason"},
+	"category":  {"categoria", "category", "tipo", "type", "grupo"},
+	"retryable": {"retentativa", "retentável", "retentavel", "retentar", "pode retentar", "retry", "retryable"},
+}
+
+// ExtractErrorCodes lê os códigos de um chunk de códigos de erro: tabelas Markdown com
+// colunas de código e mensagem, ou linhas "58: mensagem" (também separadas por ";").
+// O primeiro registro de cada código vale.
+func ExtractErrorCodes(content string) []ErrorCode {
+	var out []ErrorCode
+	seen := make(map[string]bool)
+	add := func(code, message, category, retry string) {
+		code = strings.ToUpper(strings.Trim(code, "`* "))
+		message = strings.TrimSpace(strings.TrimRight(strings.Trim(message, "`* "), "."))
+		if code == "" || message == "" || seen[code] || !errorCodeValueRe.MatchString(code) {
+			return
+		}
+		seen[code] = true
+
+		e := ErrorCode{Code: code, Message: clip(message, 300), Category: strings.ToLower(strings.TrimSpace(category))}
+		if e.Category == "" {
+			e.Category = errorCategory(message)
+		}
+		if retry != "" {
+			v := isYes(strings.Trim(retry, "`* "))
+			e.Retryable = &v
+		} else {
+			e.Retryable = retryHint(message, e.Category)
+		}
+		out = append(out, e)
+	}
+
+	var cols map[string]int
+	for _, raw := range strings.Split(content, "\n") {
+		l := strings.TrimSpace(raw)
+
+		if strings.HasPrefix(l, "|") {
+			cells := tableCells(l)
+			if _, ok := cols["code"]; !ok {
+				// ainda sem cabeçalho: numa tabela quebrada em chunks, o overlap traz linhas
+				// da parte anterior antes do cabeçalho repetido
+				cols = errorCodeColumns(cells)
+				continue
+			}
+			if isSeparatorRow(cells) {
+				continue
+			}
+			cell := func(key string) string {
+				if i, ok := cols[key]; ok && i < len(cells) {
+					return cells[i]
+				}
+				return ""
+			}
+			add(cell("code"), cell("message"), cell("category"), cell("retryable"))
+			continue
+		}
+		cols = nil
+
+		for _, seg := range strings.Split(l, ";") {
+			if m := errorLineRe.FindStringSubmatch(strings.TrimSpace(seg)); m != nil {
+				add(m[1], m[2], "", "")
+			}
+		}
+	}
+	return out
+}
+
+var errorCodeValueRe = regexp.MustCompile(`^(?:\d{1,4}|[A-Z]{1,4}\d{1,4})$`)
+
+// errorCodeColumns mapeia o cabeçalho; nil se falta a coluna de código ou a de mensagem.
+func errorCodeColumns(cells []string) map[string]int {
+	cols := make(map[string]int)
+	for i, c := range cells {
+		c = strings.ToLower(strings.Trim(c, "`* "))
+		for key, names := range errorCodeHeaders {
+			if _, ok := cols[key]; !ok && containsString(names, c) {
+				cols[key] = i
+			}
+		}
+	}
+	_, code := cols["code"]
+	_, msg := cols["message"]
+	if !code || !msg {
+		return nil
+	}
+	return cols
+}
+
+// categorias por palavra-chave da mensagem, na ordem de prioridade
+var errorCategories = []struct {
+	category string
+	words    []string
+}{
+	{"fraud", []string{"fraude", "fraud", "suspeit", "roubado", "perdido", "stolen", "lost card"}},
+	{"funds", []string{"saldo", "limite", "insuficiente", "insufficient", "exceed"}},
+	{"card", []string{"vencido", "expirado", "expired", "cvv", "cvc", "cartão inválido", "cartão bloqueado", "invalid card", "card number", "número do cartão"}},
+	{"auth", []string{"credencia", "credential", "token", "autenticação", "authentication", "unauthorized", "filiação"}},
+	{"system", []string{"timeout", "tempo esgotado", "indisponível", "unavailable", "erro interno", "internal error", "falha de comunicação", "tente novamente", "try again"}},
+	{"validation", []string{"inválid", "invalid", "obrigatório", "required", "formato", "format", "tamanho", "length", "missing"}},
+	{"issuer", []string{"não autorizada", "negad", "declin", "recusad", "emissor", "issuer", "não permitida", "not allowed"}},
+}
+
+func errorCategory(message string) string {
+	s := strings.ToLower(message)
+	for _, c := range errorCategories {
+		for _, w := range c.words {
+			if strings.Contains(s, w) {
+				return c.category
+			}
+		}
+	}
+	return ""
+}
+
+// retryHint: o que a mensagem diz ("tente novamente") ou, sem isso, o que a categoria
+// sugere. Falha de sistema costuma passar numa nova tentativa; fraude, cartão, credencial
+// e validação não mudam repetindo o mesmo request. Recusa do emissor e saldo ficam sem indicação.
+func retryHint(message, category string) *bool {
+	yes, no := true, false
+	switch {
+	case retryNoRe.MatchString(message):
+		return &no
+	case retryYesRe.MatchString(message):
+		return &yes
+	}
+	switch category {
+	case "system":
+		return &yes
+	case "fraud", "card", "auth", "validation":
+		return &no
+	}
+	return nil
+}
+
+// ErrorCode devolve o código do provider (slug ou apelido), ou nil se ele não está na
+// tabela. Com o código em mais de um chunk, fica a mensagem mais completa.
+func (s *Service) ErrorCode(ctx context.Context, provider Provider, code string) (*ErrorCode, error) {
+	if s.errorCodes == nil {
+		return nil, errors.New("error code catalog is not configured")
+	}
+	info, err := s.providerInfo(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := s.errorCodes.FindErrorCode(ctx, info.Slug, strings.ToUpper(strings.TrimSpace(code)))
+	if err != nil {
+		return nil, err
+	}
+	var best *ErrorCode
+	for i := range found {
+		if best == nil || len(found[i].Message) > len(best.Message) {
+			best = &found[i]
+		}
+	}
+	return best, nil
+}
+
+// questionErrorCode procura na tabela o código citado na pergunta ("código 58", "erro AC01");
+// nil quando a pergunta não cita código ou ele não está cadastrado no provider.
+func (s *Service) questionErrorCode(ctx context.Context, provider Provider, question string) *ErrorCode {
+	code := questionCode(question)
+	if s.errorCodes == nil || code == "" {
+		return nil
+	}
+	ec, err := s.ErrorCode(ctx, provider, code)
+	if err != nil {
+		log.Printf("error code catalog: %v", err)
+		return nil
+	}
+	return ec
+}
+
+// errorCodeAnswer monta a resposta da tabela para a retrieval resolvida pelo código
+// (retrieval.errorCode), citando o chunk; nil deixa a pergunta seguir para o LLM.
+func (s *Service) errorCodeAnswer(ctx context.Context, r *retrieval) *AskResponse {
+	ec := r.errorCode
+	if ec == nil {
+		return nil
+	}
+
+	chunks, err := s.repo.GetChunksByIDs(ctx, []int64{ec.ChunkID})
+	if err != nil {
+		log.Printf("error code chunk %d: %v", ec.ChunkID, err)
+		chunks = nil
+	}
+
+	name := string(r.provider)
+	if info, err := s.providerInfo(ctx, r.provider); err == nil {
+		name = info.DisplayName
+	}
+
+	resp := &AskResponse{Provider: r.provider, ErrorCode: ec}
+	resp.Answer, resp.Citations, resp.Sources = applyCitations(errorCodeText(ec, name, r.lang, len(chunks) > 0), buildSources(chunks))
+	return resp
+}
+
+func errorCodeText(ec *ErrorCode, provider, lang string, cite bool) string {
+	var b strings.Builder
+	label := "Código"
+	if lang == "en" {
+		label = "Code"
+	}
+	fmt.Fprintf(&b, "%s %s (%s): %s.", label, ec.Code, provider, ec.Message)
+	if cite {
+		b.WriteString(" [1]")
+	}
+
+	if ec.Category != "" {
+		label = map[string]string{"en": "Category", "es": "Categoría"}[lang]
+		if label == "" {
+			label = "Categoria"
+		}
+		fmt.Fprintf(&b, "\n%s: %s.", label, ec.Category)
+	}
+	if ec.Retryable != nil {
+		var text string
+		switch {
+		case lang == "en" && *ec.Retryable:
+			text = "Retrying may succeed."
+		case lang == "en":
+			text = "Retrying the same request will not help."
+		case lang == "es" && *ec.Retryable:
+			text = "Reintentar puede funcionar."
+		case lang == "es":
+			text = "Reintentar la misma solicitud no ayuda."
+		case *ec.Retryable:
+			text = "Uma nova tentativa pode funcionar."
+		default:
+			text = "Repetir o mesmo request não resolve."
+		}
+		b.WriteString("\n" + text)
+	}
+	return b.String()
+}
@@ -0,0 +1,45 @@
+package rag
+
+import (
+	"testing"
+)
+
+func TestExtractErrorCodes(t *testing.T) {
+	table := "Códigos de retorno\n\n| Código | Mensagem | Categoria | Retentar |\n| --- | --- | --- | --- |\n" +
+		"| 58 | Transação não permitida para o cartão | issuer | não |\n" +
+		"| `AC01` | Timeout na comunicação com o emissor | | |\n" +
+		"| 58 | duplicado | | |"
+
+	codes := ExtractErrorCodes(table)
+	if len(codes) != 2 {
+		t.Fatalf("codes = %+v", codes)
+	}
+	if c := codes[0]; c.Code != "58" || c.Message != "Transação não permitida para o cartão" || c.Category != "issuer" || c.Retryable == nil || *c.Retryable {
+		t.Errorf("58 = %+v", c)
+	}
+	// sem categoria/retentativa na tabela: estimadas pela mensagem
+	if c := codes[1]; c.Code != "AC01" || c.Category != "system" || c.Retryable == nil || !*c.Retryable {
+		t.Errorf("AC01 = %+v", c)
+	}
+
+	codes = ExtractErrorCodes("Tabela de retornos\n58 não permitida; 05 - Cartão vencido.\nCódigo 101: tente novamente mais tarde")
+	want := []struct {
+		code, category string
+		retry          *bool
+	}{
+		{"58", "issuer", nil},
+		{"05", "card", ptr(false)},
+		{"101", "system", ptr(true)},
+	}
+	if len(codes) != len(want) {
+		t.Fatalf("codes = %+v", codes)
+	}
+	for i, w := range want {
+		c := codes[i]
+		if c.Code != w.code || c.Category != w.category || (c.Retryable == nil) != (w.retry == nil) || (w.retry != nil && *c.Retryable != *w.retry) {
+			t.Errorf("codes[%d] = %+v, want %+v", i, c, w)
+		}
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
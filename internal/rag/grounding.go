@@ -0,0 +1,228 @@
+package rag
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Verificação de grounding: tudo que parece "dado concreto" na resposta (path HTTP,
+// URL, nome de campo, código numérico) precisa aparecer nos trechos enviados ao modelo.
+// É uma checagem textual e conservadora: pode deixar passar invenções, mas o que ela
+// aponta de fato não está nos trechos.
+
+// WarningKind é o tipo de item não encontrado nos trechos.
+type WarningKind string
+
+const (
+	WarningPath  WarningKind = "path"
+	WarningURL   WarningKind = "url"
+	WarningField WarningKind = "field"
+	WarningCode  WarningKind = "code"
+)
+
+var (
+	urlRe = regexp.MustCompile(`https?://[^\s<>"'\x60()\[\]]+`)
+
+	// path com pelo menos dois segmentos (/v1/transactions) ou precedido de método HTTP
+	pathRe = regexp.MustCompile(`(?:\b(?:GET|POST|PUT|PATCH|DELETE)\s+(/[\w\-{}:.<>/]*)|(?:^|[\s(\x60"'])(/[\w\-{}:.<>]+(?:/[\w\-{}:.<>]+)+/?))`)
+
+	// "campo": em exemplos JSON
+	jsonFieldRe = regexp.MustCompile(`"([A-Za-z_][\w.-]*)"\s*:`)
+
+	// `campo` em código inline com cara de identificador (snake_case, camelCase ou a.b)
+	inlineFieldRe = regexp.MustCompile("`([A-Za-z_][\\w]*(?:[._][\\w]+)+|[a-z]+[A-Z]\\w*)`")
+
+	// número logo depois de "código", "erro", "status"...
+	errorCodeRe = regexp.MustCompile(`(?i)\b(?:c[oó]digos?|codes?|erros?|errors?|status|retorno|returncode)\b[^0-9\n]{0,15}?(\d{2,4})\b`)
+
+	// {tid}, :tid e <tid> viram o mesmo placeholder na comparação de paths
+	pathParamRe = regexp.MustCompile(`\{[^}/]*\}|:[A-Za-z_]\w*|<[^>/]*>`)
+)
+
+// verifyGrounding devolve um aviso por item da resposta que não aparece em nenhum chunk
+// (nem na própria pergunta, que pode citar o path/campo que o usuário está perguntando).
+func verifyGrounding(answer, question string, chunks []DocChunk) []Warning {
+	var ctx strings.Builder
+	ctx.WriteString(question)
+	for _, c := range chunks {
+		ctx.WriteString("\n")
+		ctx.WriteString(c.Title)
+		ctx.WriteString("\n")
+		ctx.WriteString(c.SourceURL)
+		ctx.WriteString("\n")
+		ctx.WriteString(c.Content)
+	}
+	docText := ctx.String()
+	words := newWordSet(docText)
+	lower := words.lower
+	paths := normalizedPaths(docText)
+
+	warnings := []Warning{}
+	seen := make(map[string]bool)
+	warn := func(kind WarningKind, value, msg string) {
+		key := string(kind) + "|" + value
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		warnings = append(warnings, Warning{Kind: kind, Value: value, Message: msg})
+	}
+
+	for _, u := range urlRe.FindAllString(answer, -1) {
+		u = strings.TrimRight(u, ".,;:!?")
+		if !strings.Contains(lower, strings.ToLower(strings.TrimSuffix(u, "/"))) {
+			warn(WarningURL, u, "URL não encontrada na documentação recuperada")
+		}
+	}
+
+	// paths fora das URLs (senão o path de toda URL seria checado de novo)
+	noURLs := urlRe.ReplaceAllString(answer, " ")
+	for _, m := range pathRe.FindAllStringSubmatch(noURLs, -1) {
+		p := m[1]
+		if p == "" {
+			p = m[2]
+		}
+		p = strings.TrimRight(p, ".,;:")
+		if p == "" || p == "/" {
+			continue
+		}
+		if !strings.Contains(lower, strings.ToLower(p)) && !knownPath(paths, normalizePath(p)) {
+			warn(WarningPath, p, "endpoint não encontrado na documentação recuperada")
+		}
+	}
+
+	fields := jsonFieldRe.FindAllStringSubmatch(answer, -1)
+	fields = append(fields, inlineFieldRe.FindAllStringSubmatch(answer, -1)...)
+	for _, m := range fields {
+		if !words.hasField(m[1]) {
+			warn(WarningField, m[1], "campo não encontrado na documentação recuperada")
+		}
+	}
+
+	for _, m := range errorCodeRe.FindAllStringSubmatch(answer, -1) {
+		if !words.has(m[1]) {
+			warn(WarningCode, m[1], "código não encontrado na documentação recuperada")
+		}
+	}
+
+	return warnings
+}
+
+// groundingFeedback é o recado para a regeneração: o que a primeira resposta inventou.
+func groundingFeedback(warnings []Warning) string {
+	var items []string
+	for _, w := range warnings {
+		items = append(items, fmt.Sprintf("%s %s", w.Kind, w.Value))
+	}
+	return "Your previous answer mentioned items that do not appear in the documentation excerpts (" +
+		strings.Join(items, ", ") +
+		"). Answer again using only endpoints, URLs, fields and codes present in the excerpts; " +
+		"if something is not documented, say so."
+}
+
+// normalizePath troca parâmetros por "*" e ignora caixa e barra final.
+func normalizePath(p string) string {
+	p = pathParamRe.ReplaceAllString(p, "*")
+	return strings.TrimSuffix(strings.ToLower(p), "/")
+}
+
+// knownPath aceita o path igual ou como sufixo de um path da doc: a resposta costuma
+// omitir o prefixo da base URL (/erede/v1/transactions → /v1/transactions).
+func knownPath(paths map[string]bool, p string) bool {
+	if paths[p] {
+		return true
+	}
+	for dp := range paths {
+		if strings.HasSuffix(dp, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizedPaths(text string) map[string]bool {
+	out := make(map[string]bool)
+	for _, m := range pathRe.FindAllStringSubmatch(text, -1) {
+		p := m[1]
+		if p == "" {
+			p = m[2]
+		}
+		out[normalizePath(strings.TrimRight(p, ".,;:"))] = true
+	}
+	// path das URLs da doc (https://api.exemplo.com/v1/x → /v1/x)
+	for _, u := range urlRe.FindAllString(text, -1) {
+		rest := strings.TrimPrefix(strings.TrimPrefix(u, "https://"), "http://")
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			out[normalizePath(strings.TrimRight(rest[i:], ".,;:"))] = true
+		}
+	}
+	return out
+}
+
+// wordSet é o texto dos trechos em minúsculas com o conjunto das palavras dele (letras
+// ASCII, dígitos e _), montado uma vez por verificação: cada campo ou código da resposta
+// vira uma consulta no mapa, sem compilar regexp nem varrer o contexto inteiro.
+type wordSet struct {
+	lower string
+	words map[string]bool
+}
+
+func newWordSet(text string) wordSet {
+	lower := strings.ToLower(text)
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(lower, func(r rune) bool { return !isWordRune(r) }) {
+		words[w] = true
+	}
+	return wordSet{lower: lower, words: words}
+}
+
+// isWordRune é o \w do regexp: letra ASCII, dígito ou _.
+func isWordRune(r rune) bool {
+	return r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9')
+}
+
+// has procura w como palavra inteira (sem letras/dígitos/_ colados), ignorando caixa.
+// Com separador no meio ("a.b", "x-y") não há palavra única no conjunto: confere as
+// bordas de cada ocorrência no texto.
+func (s wordSet) has(w string) bool {
+	w = strings.ToLower(w)
+	if w == "" {
+		return false
+	}
+	if !strings.ContainsFunc(w, func(r rune) bool { return !isWordRune(r) }) {
+		return s.words[w]
+	}
+	for i := 0; i < len(s.lower); {
+		j := strings.Index(s.lower[i:], w)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(w)
+		before, _ := utf8.DecodeLastRuneInString(s.lower[:start])
+		after, _ := utf8.DecodeRuneInString(s.lower[end:])
+		if (start == 0 || !isWordRune(before)) && (end == len(s.lower) || !isWordRune(after)) {
+			return true
+		}
+		i = start + 1
+	}
+	return false
+}
+
+// hasField aceita o campo inteiro ou, para "a.b.c", cada parte separada
+// (a doc costuma descrever objetos aninhados em tabelas separadas).
+func (s wordSet) hasField(field string) bool {
+	if s.has(field) {
+		return true
+	}
+	if !strings.Contains(field, ".") {
+		return false
+	}
+	for _, part := range strings.Split(field, ".") {
+		if part != "" && !s.has(part) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package rag
+
+import (
+	"testing"
+)
+
+func TestVerifyGrounding(t *testing.T) {
+	chunks := []DocChunk{{
+		Title:     "Captura",
+		SourceURL: "https://developer.userede.com.br/e-rede",
+		Content: "Use PUT https://api.userede.com.br/erede/v1/transactions/{tid} com o campo amount.\n" +
+			"| returnCode | descrição |\n| 00 | sucesso |\n| 58 | transação não permitida |",
+	}}
+
+	answer := "Para capturar, envie PUT /v1/transactions/:tid [1] com `amount` e `capture_mode`.\n" +
+		"```json\n{\"amount\": 100, \"softDescriptor\": \"loja\"}\n```\n" +
+		"Veja https://developer.userede.com.br/e-rede e https://docs.exemplo.com/captura.\n" +
+		"O código 58 indica transação não permitida; o código 99 indica timeout.\n" +
+		"Também existe POST /v2/captures."
+
+	got := make(map[string]WarningKind)
+	for _, w := range verifyGrounding(answer, "como capturar?", chunks) {
+		got[w.Value] = w.Kind
+	}
+
+	want := map[string]WarningKind{
+		"capture_mode":                     WarningField,
+		"softDescriptor":                   WarningField,
+		"https://docs.exemplo.com/captura": WarningURL,
+		"99":                               WarningCode,
+		"/v2/captures":                     WarningPath,
+	}
+	for v, k := range want {
+		if got[v] != k {
+			t.Errorf("missing %s warning for %q (got %v)", k, v, got)
+		}
+	}
+	for _, ok := range []string{"/v1/transactions/:tid", "amount", "58", "https://developer.userede.com.br/e-rede"} {
+		if _, flagged := got[ok]; flagged {
+			t.Errorf("%q is in the docs but was flagged", ok)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("warnings = %v, want %v", got, want)
+	}
+}
+
+func TestWordSet(t *testing.T) {
+	words := newWordSet("O campo `payment.amount` vai em centavos; returnCode 58. Header X-Api-Key e código_interno.")
+	cases := []struct {
+		w    string
+		want bool
+	}{
+		{"58", true},
+		{"5", false}, // parte de outra palavra
+		{"ReturnCode", true},
+		{"return", false},
+		{"payment.amount", true},
+		{"x-api-key", true},
+		{"api-key", true},  // "-" não é letra: a borda vale
+		{"api-ke", false},  // cortado no meio da palavra
+		{"interno", false}, // "_" é letra para o \w
+		{"código_interno", true},
+		{"", false},
+	}
+	for _, tc := range cases {
+		if got := words.has(tc.w); got != tc.want {
+			t.Errorf("has(%q) = %v, want %v", tc.w, got, tc.want)
+		}
+	}
+	if !words.hasField("amount.payment") || words.hasField("payment.currency") {
+		t.Error("hasField should accept the parts in any table and reject a missing one")
+	}
+}
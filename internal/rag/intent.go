@@ -0,0 +1,243 @@
+package rag
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Classificação de intenção da pergunta, com as mesmas heurísticas de palavra-chave
+// que o importador usa para preencher section_type e tags dos chunks. Assim a pergunta
+// "como fazer estorno?" cai nos chunks marcados com a tag "refund".
+
+// IntentName é o assunto principal da pergunta.
+type IntentName string
+
+const (
+	IntentErrorCode     IntentName = "error_code"
+	Intent3DS           IntentName = "3ds"
+	IntentRefund        IntentName = "refund"
+	IntentCancel        IntentName = "cancel"
+	IntentCapture       IntentName = "capture"
+	IntentAuthorization IntentName = "authorization"
+	IntentWebhook       IntentName = "webhook"
+	IntentSandbox       IntentName = "sandbox"
+	IntentGeneral       IntentName = "general"
+)
+
+// IntentMode define como a intenção entra na busca.
+// Boost sobe os chunks com section_type/tags da intenção; Filter busca só neles
+// (e cai para a busca sem filtro se nada for encontrado).
+type IntentMode string
+
+const (
+	IntentOff    IntentMode = "off"
+	IntentBoost  IntentMode = "boost"
+	IntentFilter IntentMode = "filter"
+)
+
+// intentBoostRanks é quantas posições um chunk sobe por critério da intenção que atende
+// (section_type e tags contam separado).
+const intentBoostRanks = 3
+
+// Intent
+// Resultado da classificação, devolvido no AskResponse para transparência.
+type Intent struct {
+	Name         IntentName    `json:"name"`
+	SectionTypes []SectionType `json:"sectionTypes,omitempty"`
+	Tags         []string      `json:"tags,omitempty"`
+	Code         string        `json:"code,omitempty"`    // código citado numa consulta de erro ("código 58")
+	Applied      IntentMode    `json:"applied,omitempty"` // boost ou filter; vazio quando não mudou a busca
+}
+
+// ordem de prioridade para escolher o nome da intenção a partir das tags
+var intentByTag = []struct {
+	tag  string
+	name IntentName
+}{
+	{"3ds", Intent3DS},
+	{"refund", IntentRefund},
+	{"cancel", IntentCancel},
+	{"capture", IntentCapture},
+	{"authorization", IntentAuthorization},
+	{"webhook", IntentWebhook},
+	{"sandbox", IntentSandbox},
+}
+
+// classifyIntent aplica DetectTags na pergunta e reconhece consultas de código de erro.
+// "transaction" fica de fora: aparece em quase toda pergunta e não ajuda a separar.
+func classifyIntent(question string) Intent {
+	s := strings.ToLower(question)
+	in := Intent{Name: IntentGeneral}
+
+	for _, t := range DetectTags(question) {
+		if t != "transaction" {
+			in.Tags = append(in.Tags, t)
+		}
+	}
+	for _, it := range intentByTag {
+		if containsString(in.Tags, it.tag) {
+			in.Name = it.name
+			break
+		}
+	}
+
+	if code := questionCode(question); code != "" {
+		in.Name = IntentErrorCode
+		in.Code = code
+		in.SectionTypes = []SectionType{SectionErrors}
+	} else if strings.Contains(s, "error code") || strings.Contains(s, "código de erro") ||
+		strings.Contains(s, "códigos de erro") || strings.Contains(s, "codigo de erro") {
+		in.Name = IntentErrorCode
+		in.SectionTypes = []SectionType{SectionErrors}
+	}
+
+	if in.Name == Intent3DS {
+		in.SectionTypes = []SectionType{Section3DS}
+	}
+	return in
+}
+
+// código citado na pergunta: número ("código 58") ou alfanumérico ("erro AC01"), como
+// os que o ExtractErrorCodes lê das tabelas
+var questionCodeRe = regexp.MustCompile(`(?i:\b(?:c[oó]digos?|codes?|erros?|errors?|retorno|returncode)\b)([^0-9\n]{0,15}?)\b(\d{2,4}|[A-Z]{1,4}\d{1,4})\b`)
+
+// questionCode devolve o primeiro código de erro/retorno citado na pergunta. "status 404",
+// "código de status 404" e "erro HTTP 500" são status HTTP, não códigos da tabela.
+func questionCode(question string) string {
+	for _, m := range questionCodeRe.FindAllStringSubmatch(question, -1) {
+		if !httpStatusGapRe.MatchString(m[1]) {
+			return strings.ToUpper(m[2])
+		}
+	}
+	return ""
+}
+
+var httpStatusGapRe = regexp.MustCompile(`(?i)\b(?:status|http)\b`)
+
+// filters converte a intenção em filtro de busca (vazio para perguntas gerais).
+// Com section_type definido (3DS, códigos de erro) só ele filtra: exigir as tags
+// junto cortaria demais.
+func (in Intent) filters() SearchFilters {
+	if len(in.SectionTypes) > 0 {
+		return SearchFilters{SectionTypes: in.SectionTypes}
+	}
+	return SearchFilters{TagsAny: in.Tags}
+}
+
+func (in Intent) empty() bool {
+	return len(in.SectionTypes) == 0 && len(in.Tags) == 0
+}
+
+// boostByIntent reordena os chunks: cada critério da intenção atendido vale
+// intentBoostRanks posições. Funciona igual para ranking vetorial e híbrido (RRF),
+// já que só mexe na ordem recebida.
+func boostByIntent(chunks []DocChunk, in Intent) {
+	key := make(map[int64]int, len(chunks))
+	for i, c := range chunks {
+		k := i
+		if containsSection(in.SectionTypes, c.SectionType) {
+			k -= intentBoostRanks
+		}
+		for _, t := range c.Tags {
+			if containsString(in.Tags, t) {
+				k -= intentBoostRanks
+				break
+			}
+		}
+		key[c.ID] = k
+	}
+	sort.SliceStable(chunks, func(a, b int) bool { return key[chunks[a].ID] < key[chunks[b].ID] })
+}
+
+func (f SearchFilters) empty() bool {
+	return len(f.SectionTypes) == 0 && len(f.TagsAll) == 0 && len(f.TagsAny) == 0 && f.APIVersion == ""
+}
+
+func containsSection(list []SectionType, st SectionType) bool {
+	for _, x := range list {
+		if x == st {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectSectionType classifica um trecho de documentação (usado pelo importador).
+// Tabelas de códigos vêm antes de autorização/endpoint: as mensagens costumam citar
+// "autorização" ("autorização negada") e o chunk precisa cair em errors para o
+// importador ler os códigos.
+func DetectSectionType(chunk string) SectionType {
+	s := strings.ToLower(chunk)
+
+	switch {
+	case strings.Contains(s, "3ds") || strings.Contains(s, "3-d secure"):
+		return Section3DS
+	case strings.Contains(s, "error code") || strings.Contains(s, "código de erro") ||
+		strings.Contains(s, "códigos de erro") || strings.Contains(s, "códigos de retorno") ||
+		strings.Contains(s, "return codes"):
+		return SectionErrors
+	case strings.Contains(s, "authorization") || strings.Contains(s, "autorização"):
+		return SectionAuth
+	case strings.Contains(s, "endpoint") ||
+		(strings.Contains(s, "http") &&
+			(strings.Contains(s, "post") || strings.Contains(s, "get") ||
+				strings.Contains(s, "put") || strings.Contains(s, "delete"))):
+		return SectionEndpoint
+	default:
+		return SectionOverview
+	}
+}
+
+// DetectTags marca o assunto de um texto por palavra-chave. O importador usa nos
+// chunks e o classifyIntent na pergunta, então as duas pontas falam as mesmas tags.
+func DetectTags(text string) []string {
+	s := strings.ToLower(text)
+	var tags []string
+
+	add := func(t string) {
+		for _, ex := range tags {
+			if ex == t {
+				return
+			}
+		}
+		tags = append(tags, t)
+	}
+
+	if strings.Contains(s, "3ds") || strings.Contains(s, "3-d secure") {
+		add("3ds")
+		add("auth")
+	}
+	if strings.Contains(s, "authorization") || strings.Contains(s, "autorização") {
+		add("authorization")
+	}
+	if strings.Contains(s, "capture") || strings.Contains(s, "captura") {
+		add("capture")
+	}
+	if strings.Contains(s, "refund") || strings.Contains(s, "estorno") {
+		add("refund")
+	}
+	if strings.Contains(s, "cancel") || strings.Contains(s, "void") {
+		add("cancel")
+	}
+	if strings.Contains(s, "webhook") || strings.Contains(s, "notificação") {
+		add("webhook")
+	}
+	if strings.Contains(s, "sandbox") {
+		add("sandbox")
+	}
+	if strings.Contains(s, "transaction") || strings.Contains(s, "transação") {
+		add("transaction")
+	}
+
+	return tags
+}
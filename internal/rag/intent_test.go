@@ -0,0 +1,56 @@
+package rag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassifyIntent(t *testing.T) {
+	cases := []struct {
+		question string
+		name     IntentName
+		sections []SectionType
+		tags     []string
+		code     string
+	}{
+		{"o que significa o código 58 na rede?", IntentErrorCode, []SectionType{SectionErrors}, nil, "58"},
+		{"lista de códigos de erro da entrepay", IntentErrorCode, []SectionType{SectionErrors}, nil, ""},
+		{"o que é o erro AC01 na entrepay?", IntentErrorCode, []SectionType{SectionErrors}, nil, "AC01"},
+		{"código de retorno 174 da rede", IntentErrorCode, []SectionType{SectionErrors}, nil, "174"},
+		{"status 404 na captura", IntentCapture, nil, []string{"capture"}, ""},
+		{"código de status 404 e depois o código 58", IntentErrorCode, []SectionType{SectionErrors}, nil, "58"},
+		{"recebo erro HTTP 500 na API v1", IntentGeneral, nil, nil, ""},
+		{"como autenticar com 3DS na rede?", Intent3DS, []SectionType{Section3DS}, []string{"3ds", "auth"}, ""},
+		{"como fazer estorno de uma transação?", IntentRefund, nil, []string{"refund"}, ""},
+		{"configurar webhook de notificação", IntentWebhook, nil, []string{"webhook"}, ""},
+		{"quais são as bandeiras aceitas?", IntentGeneral, nil, nil, ""},
+	}
+	for _, tc := range cases {
+		in := classifyIntent(tc.question)
+		if in.Name != tc.name || in.Code != tc.code ||
+			!reflect.DeepEqual(in.SectionTypes, tc.sections) || !reflect.DeepEqual(in.Tags, tc.tags) {
+			t.Errorf("classifyIntent(%q) = %+v", tc.question, in)
+		}
+	}
+}
+
+func TestBoostByIntent(t *testing.T) {
+	chunks := []DocChunk{
+		{ID: 1, SectionType: SectionOverview},
+		{ID: 2, SectionType: SectionEndpoint},
+		{ID: 3, SectionType: SectionEndpoint, Tags: []string{"refund"}},
+		{ID: 4, SectionType: SectionOverview},
+		{ID: 5, SectionType: SectionOverview},
+		{ID: 6, SectionType: SectionOverview, Tags: []string{"refund"}},
+	}
+	boostByIntent(chunks, Intent{Name: IntentRefund, Tags: []string{"refund"}})
+
+	var ids []int64
+	for _, c := range chunks {
+		ids = append(ids, c.ID)
+	}
+	// 3 sobe para o topo; 6 sobe três posições e passa 4 e 5
+	if want := []int64{3, 1, 2, 6, 4, 5}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("order = %v, want %v", ids, want)
+	}
+}
@@ -2,10 +2,27 @@ package rag
 
 import "context"
 
+// MaxPromptChunks é quantos trechos os backends mandam no prompt. O Ask corta os chunks
+// nesse limite antes de gerar, para as fontes numeradas e a verificação de grounding
+// corresponderem ao que o modelo viu.
+const MaxPromptChunks = 10
+
 type EmbeddingsClient interface {
 	Embed(ctx context.Context, text string) ([]float32, error)
+	// EmbedBatch gera os embeddings de vários textos, na mesma ordem de texts.
+	// A implementação divide em lotes do tamanho aceito pelo provider.
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
 }
 
+// LLMClient gera as respostas. history são os turnos anteriores da conversa
+// (vazio numa pergunta avulsa), em ordem cronológica. provider vazio é o modo
+// comparação: os chunks vêm de vários providers (agrupados) e a resposta os compara.
 type LLMClient interface {
-	GenerateAnswer(ctx context.Context, question string, chunks []DocChunk, provider Provider, lang string) (string, error)
+	GenerateAnswer(ctx context.Context, question string, history []ConversationMessage, chunks []DocChunk, provider Provider, lang string) (string, error)
+	// GenerateAnswerStream gera a mesma resposta do GenerateAnswer, mas entrega o texto
+	// em pedaços via onToken conforme o modelo produz. Um erro de onToken interrompe a geração.
+	GenerateAnswerStream(ctx context.Context, question string, history []ConversationMessage, chunks []DocChunk, provider Provider, lang string, onToken func(string) error) (*Usage, error)
+	// CondenseQuestion reescreve um follow-up ("e como capturo?") como pergunta
+	// autossuficiente, usando o histórico, para ser usada na busca.
+	CondenseQuestion(ctx context.Context, history []ConversationMessage, question string) (string, error)
 }
@@ -0,0 +1,940 @@
+package rag
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DistanceMetric define a ordenação da busca vetorial do MemoryRepository.
+// O PgRepository ordena por L2 (<->); com embeddings normalizados as duas dão a mesma ordem.
+type DistanceMetric string
+
+const (
+	MetricCosine DistanceMetric = "cosine"
+	MetricL2     DistanceMetric = "l2"
+)
+
+// MemoryRepository guarda chunks, documentos e conversas em memória, com busca
+// vetorial por força bruta. Serve para testes e para rodar localmente sem Postgres.
+// Com path, cada parte do estado (memoryPart) fica num arquivo JSON próprio, carregado
+// na criação e regravado quando a parte muda: uma pergunta que grava a conversa não
+// reescreve os embeddings do corpus. Os arquivos podem ser compartilhados entre processos
+// (importador e API), desde que cada parte tenha um escritor só: leituras e escritas
+// recarregam as partes que outro processo regravou, e um arquivo .lock serializa as gravações.
+type MemoryRepository struct {
+	mu     sync.RWMutex
+	path   string
+	metric DistanceMetric
+
+	state memoryState
+
+	// disk é cada arquivo como estava na última leitura ou gravação (ausente se ainda não existia).
+	disk map[memoryPart]os.FileInfo
+	// legacy são as partes lidas do arquivo único antigo (tudo em path), que ainda não
+	// têm arquivo próprio: vão para ele na próxima gravação.
+	legacy memoryPart
+}
+
+// memoryPart é uma parte do estado com arquivo e escritor próprios: o importador grava
+// os documentos, a API grava as conversas.
+type memoryPart uint8
+
+const (
+	partDocuments     memoryPart = 1 << iota // documentos, chunks, endpoints e códigos de erro
+	partConversations                        // conversas e mensagens
+	partProviders                            // cadastro de providers
+)
+
+var memoryParts = []memoryPart{partDocuments, partConversations, partProviders}
+
+// memoryState é o estado inteiro; cada parte embutida vai para o seu arquivo.
+// Os campos ficam na raiz do JSON, como no arquivo único antigo.
+type memoryState struct {
+	memoryDocuments
+	memoryConversations
+	memoryProviders
+}
+
+type memoryDocuments struct {
+	NextChunkID     int64         `json:"nextChunkId"`
+	NextDocID       int64         `json:"nextDocId"`
+	NextEndpointID  int64         `json:"nextEndpointId"`
+	NextErrorCodeID int64         `json:"nextErrorCodeId"`
+	Chunks          []memoryChunk `json:"chunks"`
+	Documents       []Document    `json:"documents"`
+}
+
+type memoryConversations struct {
+	Conversations map[string]*Conversation         `json:"conversations"`
+	Messages      map[string][]ConversationMessage `json:"messages"`
+}
+
+type memoryProviders struct {
+	Providers []ProviderInfo `json:"providers"`
+}
+
+type memoryChunk struct {
+	Chunk      DocChunk  `json:"chunk"`
+	DocumentID int64     `json:"documentId,omitempty"`
+	Embedding  []float32 `json:"embedding"`
+
+	// Endpoints e ErrorCodes são as tabelas endpoint e provider_error_code:
+	// vão embora junto com o chunk.
+	Endpoints  []Endpoint  `json:"endpoints,omitempty"`
+	ErrorCodes []ErrorCode `json:"errorCodes,omitempty"`
+}
+
+// NewMemoryRepository cria o repositório; path vazio fica só em memória.
+// As conversas vão para <path sem extensão>.conversations<ext> e os providers
+// para <path sem extensão>.providers<ext>.
+func NewMemoryRepository(path string, metric DistanceMetric) (*MemoryRepository, error) {
+	switch metric {
+	case "":
+		metric = MetricL2
+	case MetricCosine, MetricL2:
+	default:
+		return nil, fmt.Errorf("invalid metric %q (use cosine ou l2)", metric)
+	}
+
+	r := &MemoryRepository{
+		path:   path,
+		metric: metric,
+		state: memoryState{
+			memoryConversations: memoryConversations{
+				Conversations: make(map[string]*Conversation),
+				Messages:      make(map[string][]ConversationMessage),
+			},
+			memoryProviders: memoryProviders{Providers: DefaultProviders()},
+		},
+		disk: make(map[memoryPart]os.FileInfo),
+	}
+	if path == "" {
+		return r, nil
+	}
+
+	if err := r.refresh(); err != nil {
+		return nil, err
+	}
+	if err := r.loadLegacy(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// partPath é o arquivo de uma parte: os documentos ficam em path, que no formato
+// antigo também guardava as outras partes.
+func (r *MemoryRepository) partPath(part memoryPart) string {
+	ext := filepath.Ext(r.path)
+	base := strings.TrimSuffix(r.path, ext)
+	switch part {
+	case partConversations:
+		return base + ".conversations" + ext
+	case partProviders:
+		return base + ".providers" + ext
+	default:
+		return r.path
+	}
+}
+
+// decodePart troca a parte inteira pelo conteúdo de data (sem mesclar mapas antigos).
+func (s *memoryState) decodePart(part memoryPart, data []byte) error {
+	switch part {
+	case partDocuments:
+		var d memoryDocuments
+		if err := json.Unmarshal(data, &d); err != nil {
+			return err
+		}
+		s.memoryDocuments = d
+	case partConversations:
+		var c memoryConversations
+		if err := json.Unmarshal(data, &c); err != nil {
+			return err
+		}
+		if c.Conversations == nil {
+			c.Conversations = make(map[string]*Conversation)
+		}
+		if c.Messages == nil {
+			c.Messages = make(map[string][]ConversationMessage)
+		}
+		s.memoryConversations = c
+	case partProviders:
+		var p memoryProviders
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		if p.Providers == nil {
+			p.Providers = DefaultProviders()
+		}
+		s.memoryProviders = p
+	}
+	return nil
+}
+
+func (s *memoryState) encodePart(part memoryPart) ([]byte, error) {
+	switch part {
+	case partConversations:
+		return json.Marshal(&s.memoryConversations)
+	case partProviders:
+		return json.Marshal(&s.memoryProviders)
+	default:
+		return json.Marshal(&s.memoryDocuments)
+	}
+}
+
+// changed diz quais partes outro processo regravou desde a última leitura ou gravação.
+func (r *MemoryRepository) changed() (memoryPart, error) {
+	var out memoryPart
+	for _, part := range memoryParts {
+		info, err := os.Stat(r.partPath(part))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		old := r.disk[part]
+		if old == nil || !os.SameFile(info, old) || !info.ModTime().Equal(old.ModTime()) || info.Size() != old.Size() {
+			out |= part
+		}
+	}
+	return out, nil
+}
+
+// refresh recarrega as partes que mudaram no disco. Chamar com o lock de escrita.
+func (r *MemoryRepository) refresh() error {
+	parts, err := r.changed()
+	if err != nil || parts == 0 {
+		return err
+	}
+	for _, part := range memoryParts {
+		if parts&part == 0 {
+			continue
+		}
+		path := r.partPath(part)
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := r.state.decodePart(part, data); err != nil {
+			return fmt.Errorf("invalid memory store %s: %w", path, err)
+		}
+		r.disk[part] = info
+		r.legacy &^= part
+	}
+	return nil
+}
+
+// loadLegacy lê do arquivo único antigo as partes que ainda não têm arquivo próprio.
+func (r *MemoryRepository) loadLegacy() error {
+	if r.disk[partDocuments] == nil {
+		return nil
+	}
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	var legacy struct {
+		Conversations json.RawMessage `json:"conversations"`
+		Providers     json.RawMessage `json:"providers"`
+	}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return fmt.Errorf("invalid memory store %s: %w", r.path, err)
+	}
+
+	found := map[memoryPart]bool{
+		partConversations: legacy.Conversations != nil,
+		partProviders:     legacy.Providers != nil,
+	}
+	for part, ok := range found {
+		if !ok || r.disk[part] != nil {
+			continue
+		}
+		if err := r.state.decodePart(part, data); err != nil {
+			return fmt.Errorf("invalid memory store %s: %w", r.path, err)
+		}
+		r.legacy |= part
+	}
+	return nil
+}
+
+// lock trava o repositório para escrita com o estado em dia com o disco.
+func (r *MemoryRepository) lock() error {
+	r.mu.Lock()
+	if r.path == "" {
+		return nil
+	}
+	if err := r.refresh(); err != nil {
+		r.mu.Unlock()
+		return fmt.Errorf("reload memory store: %w", err)
+	}
+	return nil
+}
+
+// rlock trava para leitura; se outro processo regravou alguma parte (ex: o importador
+// terminou um documento), recarrega antes.
+func (r *MemoryRepository) rlock() error {
+	r.mu.RLock()
+	if r.path == "" {
+		return nil
+	}
+	parts, err := r.changed()
+	if err == nil && parts == 0 {
+		return nil
+	}
+	r.mu.RUnlock()
+
+	r.mu.Lock()
+	err = r.refresh()
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("reload memory store: %w", err)
+	}
+	r.mu.RLock()
+	return nil
+}
+
+// -------- Repository --------
+
+func (r *MemoryRepository) InsertChunk(_ context.Context, c *DocChunk, embedding []float32) (int64, error) {
+	if err := r.lock(); err != nil {
+		return 0, err
+	}
+	defer r.mu.Unlock()
+
+	id := r.insertChunk(c, 0, embedding)
+	if err := r.persist(partDocuments); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *MemoryRepository) insertChunk(c *DocChunk, documentID int64, embedding []float32) int64 {
+	r.state.NextChunkID++
+	now := time.Now()
+
+	stored := *c
+	stored.ID = r.state.NextChunkID
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	stored.Distance, stored.Similarity, stored.LexicalMatch, stored.Score = 0, 0, false, 0
+	stored.Endpoints, stored.ErrorCodes = nil, nil
+
+	var endpoints []Endpoint
+	for _, e := range c.Endpoints {
+		r.state.NextEndpointID++
+		e.ID = r.state.NextEndpointID
+		e.Provider = stored.Provider
+		e.ChunkID = stored.ID
+		if e.Parameters == nil {
+			e.Parameters = []EndpointParam{}
+		}
+		endpoints = append(endpoints, e)
+	}
+	var codes []ErrorCode
+	for _, e := range c.ErrorCodes {
+		r.state.NextErrorCodeID++
+		e.ID = r.state.NextErrorCodeID
+		e.Provider = stored.Provider
+		e.ChunkID = stored.ID
+		codes = append(codes, e)
+	}
+
+	r.state.Chunks = append(r.state.Chunks, memoryChunk{
+		Chunk:      stored,
+		DocumentID: documentID,
+		Embedding:  append([]float32(nil), embedding...),
+		Endpoints:  endpoints,
+		ErrorCodes: codes,
+	})
+	c.ID = stored.ID
+	return stored.ID
+}
+
+func (r *MemoryRepository) GetChunksByIDs(_ context.Context, ids []int64) ([]DocChunk, error) {
+	if err := r.rlock(); err != nil {
+		return nil, err
+	}
+	defer r.mu.RUnlock()
+
+	want := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+
+	var out []DocChunk
+	for _, mc := range r.state.Chunks {
+		if want[mc.Chunk.ID] {
+			out = append(out, mc.Chunk)
+		}
+	}
+	return out, nil
+}
+
+// SearchSimilarChunks compara o embedding com todos os chunks do provider.
+// Preenche Distance (L2) e Similarity (cosseno) como o PgRepository.
+func (r *MemoryRepository) SearchSimilarChunks(_ context.Context, provider Provider, embedding []float32, limit int, filters SearchFilters) ([]DocChunk, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+
+	if err := r.rlock(); err != nil {
+		return nil, err
+	}
+	defer r.mu.RUnlock()
+
+	ranked := r.vectorRank(provider, embedding, filters)
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked, nil
+}
+
+// SearchHybridChunks imita a versão SQL: ranking vetorial + ranking por termos da
+// pergunta que aparecem no chunk, fundidos via reciprocal-rank fusion.
+func (r *MemoryRepository) SearchHybridChunks(ctx context.Context, provider Provider, query string, embedding []float32, limit int, filters SearchFilters) ([]DocChunk, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+
+	lexical := lexicalQuery(query)
+	if lexical == "" {
+		return r.SearchSimilarChunks(ctx, provider, embedding, limit, filters)
+	}
+	terms := strings.Split(lexical, " or ")
+
+	if err := r.rlock(); err != nil {
+		return nil, err
+	}
+	defer r.mu.RUnlock()
+
+	candidates := limit * hybridCandidates
+
+	all := r.vectorRank(provider, embedding, filters)
+	vector := all[:min(len(all), candidates)]
+
+	type lexHit struct {
+		chunk DocChunk
+		hits  int
+	}
+	var lexHits []lexHit
+	for _, c := range all {
+		words := tokenSet(c.Title + " " + c.Content)
+		n := 0
+		for _, t := range terms {
+			if !stopwords[t] && words[t] {
+				n++
+			}
+		}
+		if n > 0 {
+			lexHits = append(lexHits, lexHit{chunk: c, hits: n})
+		}
+	}
+	sort.SliceStable(lexHits, func(a, b int) bool { return lexHits[a].hits > lexHits[b].hits })
+	if len(lexHits) > candidates {
+		lexHits = lexHits[:candidates]
+	}
+
+	fused := make(map[int64]*DocChunk)
+	var order []int64
+	add := func(c DocChunk, rank int, lexical bool) {
+		f, ok := fused[c.ID]
+		if !ok {
+			cp := c
+			f = &cp
+			fused[c.ID] = f
+			order = append(order, c.ID)
+		}
+		f.Score += 1.0 / float64(rrfK+rank)
+		f.LexicalMatch = f.LexicalMatch || lexical
+	}
+	for i, c := range vector {
+		add(c, i+1, false)
+	}
+	for i, h := range lexHits {
+		add(h.chunk, i+1, true)
+	}
+
+	out := make([]DocChunk, 0, len(order))
+	for _, id := range order {
+		c := *fused[id]
+		c.Score = 0 // o score da fusão é interno, como no SQL
+		out = append(out, c)
+	}
+	sort.SliceStable(out, func(a, b int) bool {
+		sa, sb := fused[out[a].ID].Score, fused[out[b].ID].Score
+		if sa != sb {
+			return sa > sb
+		}
+		return out[a].ID < out[b].ID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// vectorRank devolve todos os chunks do provider (vazio = todos) que passam nos filtros, ordenados
+// pela métrica configurada. Chamar com o lock de leitura.
+func (r *MemoryRepository) vectorRank(provider Provider, embedding []float32, filters SearchFilters) []DocChunk {
+	var out []DocChunk
+	for _, mc := range r.state.Chunks {
+		if (provider != "" && mc.Chunk.Provider != provider) || len(mc.Embedding) != len(embedding) || !matchesFilters(mc.Chunk, filters) {
+			continue
+		}
+		c := mc.Chunk
+		c.Distance = l2Distance(embedding, mc.Embedding)
+		c.Similarity = cosineSimilarity(embedding, mc.Embedding)
+		out = append(out, c)
+	}
+
+	sort.SliceStable(out, func(a, b int) bool {
+		if r.metric == MetricCosine {
+			return out[a].Similarity > out[b].Similarity
+		}
+		return out[a].Distance < out[b].Distance
+	})
+	return out
+}
+
+// matchesFilters é a versão em Go do filterSQL.
+func matchesFilters(c DocChunk, f SearchFilters) bool {
+	if len(f.SectionTypes) > 0 {
+		ok := false
+		for _, st := range f.SectionTypes {
+			if c.SectionType == st {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if f.APIVersion != "" && c.APIVersion != f.APIVersion {
+		return false
+	}
+
+	tags := make(map[string]bool, len(c.Tags))
+	for _, t := range c.Tags {
+		tags[t] = true
+	}
+	for _, t := range normalizeTags(f.TagsAll) {
+		if !tags[t] {
+			return false
+		}
+	}
+	if any := normalizeTags(f.TagsAny); len(any) > 0 {
+		ok := false
+		for _, t := range any {
+			if tags[t] {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func l2Distance(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		d := float64(a[i]) - float64(b[i])
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// -------- DocumentRepository --------
+
+func (r *MemoryRepository) GetDocument(_ context.Context, provider Provider, source string) (*Document, error) {
+	if err := r.rlock(); err != nil {
+		return nil, err
+	}
+	defer r.mu.RUnlock()
+
+	for _, d := range r.state.Documents {
+		if d.Provider == provider && d.Source == source {
+			return &d, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MemoryRepository) ReplaceDocument(_ context.Context, doc *Document, chunks []DocChunk, embeddings [][]float32) (int64, error) {
+	if len(chunks) != len(embeddings) {
+		return 0, fmt.Errorf("chunks/embeddings mismatch: %d != %d", len(chunks), len(embeddings))
+	}
+
+	if err := r.lock(); err != nil {
+		return 0, err
+	}
+	defer r.mu.Unlock()
+
+	var stored *Document
+	for i := range r.state.Documents {
+		if d := &r.state.Documents[i]; d.Provider == doc.Provider && d.Source == doc.Source {
+			stored = d
+			break
+		}
+	}
+	if stored == nil {
+		r.state.NextDocID++
+		r.state.Documents = append(r.state.Documents, Document{ID: r.state.NextDocID, Provider: doc.Provider, Source: doc.Source})
+		stored = &r.state.Documents[len(r.state.Documents)-1]
+	}
+	stored.ContentHash = doc.ContentHash
+	stored.APIVersion = doc.APIVersion
+	stored.ImportedAt = time.Now()
+
+	r.deleteChunks(map[int64]bool{stored.ID: true})
+	for i := range chunks {
+		r.insertChunk(&chunks[i], stored.ID, embeddings[i])
+	}
+
+	if err := r.persist(partDocuments); err != nil {
+		return 0, err
+	}
+
+	doc.ID = stored.ID
+	return stored.ID, nil
+}
+
+func (r *MemoryRepository) PruneDocuments(_ context.Context, provider Provider, sourcePrefix string, keep []string) (int64, error) {
+	keepSet := make(map[string]bool, len(keep))
+	for _, k := range keep {
+		keepSet[k] = true
+	}
+
+	if err := r.lock(); err != nil {
+		return 0, err
+	}
+	defer r.mu.Unlock()
+
+	removed := make(map[int64]bool)
+	docs := r.state.Documents[:0]
+	for _, d := range r.state.Documents {
+		if d.Provider == provider && underPrefix(d.Source, sourcePrefix) && !keepSet[d.Source] {
+			removed[d.ID] = true
+			continue
+		}
+		docs = append(docs, d)
+	}
+	r.state.Documents = docs
+
+	if len(removed) == 0 {
+		return 0, nil
+	}
+	r.deleteChunks(removed)
+	return int64(len(removed)), r.persist(partDocuments)
+}
+
+// deleteChunks remove os chunks dos documentos informados (o ON DELETE CASCADE da versão SQL).
+func (r *MemoryRepository) deleteChunks(documentIDs map[int64]bool) {
+	chunks := r.state.Chunks[:0]
+	for _, mc := range r.state.Chunks {
+		if mc.DocumentID != 0 && documentIDs[mc.DocumentID] {
+			continue
+		}
+		chunks = append(chunks, mc)
+	}
+	r.state.Chunks = chunks
+}
+
+// -------- EndpointCatalog --------
+
+func (r *MemoryRepository) ListEndpoints(_ context.Context, provider Provider) ([]Endpoint, error) {
+	if err := r.rlock(); err != nil {
+		return nil, err
+	}
+	defer r.mu.RUnlock()
+
+	var out []Endpoint
+	for _, mc := range r.state.Chunks {
+		if mc.Chunk.Provider != provider {
+			continue
+		}
+		for _, e := range mc.Endpoints {
+			e.Title = mc.Chunk.Title
+			e.SourceURL = mc.Chunk.SourceURL
+			e.APIVersion = mc.Chunk.APIVersion
+			out = append(out, e)
+		}
+	}
+	sort.SliceStable(out, func(a, b int) bool {
+		if out[a].Path != out[b].Path {
+			return out[a].Path < out[b].Path
+		}
+		if out[a].Method != out[b].Method {
+			return out[a].Method < out[b].Method
+		}
+		return out[a].ID < out[b].ID
+	})
+	return out, nil
+}
+
+// -------- ErrorCodeCatalog --------
+
+func (r *MemoryRepository) FindErrorCode(_ context.Context, provider Provider, code string) ([]ErrorCode, error) {
+	if err := r.rlock(); err != nil {
+		return nil, err
+	}
+	defer r.mu.RUnlock()
+
+	var out []ErrorCode
+	for _, mc := range r.state.Chunks {
+		if mc.Chunk.Provider != provider {
+			continue
+		}
+		for _, e := range mc.ErrorCodes {
+			if e.Code == code {
+				e.Title = mc.Chunk.Title
+				e.SourceURL = mc.Chunk.SourceURL
+				e.APIVersion = mc.Chunk.APIVersion
+				out = append(out, e)
+			}
+		}
+	}
+	return out, nil
+}
+
+// -------- ConversationStore --------
+
+func (r *MemoryRepository) CreateConversation(_ context.Context, provider Provider) (string, error) {
+	id, err := newUUID()
+	if err != nil {
+		return "", err
+	}
+
+	if err := r.lock(); err != nil {
+		return "", err
+	}
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.state.Conversations[id] = &Conversation{ID: id, Provider: provider, CreatedAt: now, UpdatedAt: now}
+	return id, r.persist(partConversations)
+}
+
+func (r *MemoryRepository) GetConversation(_ context.Context, id string) (*Conversation, error) {
+	if err := r.rlock(); err != nil {
+		return nil, err
+	}
+	defer r.mu.RUnlock()
+
+	c, ok := r.state.Conversations[strings.ToLower(id)]
+	if !ok {
+		return nil, nil
+	}
+	cp := *c
+	return &cp, nil
+}
+
+func (r *MemoryRepository) ListMessages(_ context.Context, id string, limit int) ([]ConversationMessage, error) {
+	if err := r.rlock(); err != nil {
+		return nil, err
+	}
+	defer r.mu.RUnlock()
+
+	msgs := r.state.Messages[id]
+	if limit > 0 && len(msgs) > limit {
+		msgs = msgs[len(msgs)-limit:]
+	}
+	return append([]ConversationMessage(nil), msgs...), nil
+}
+
+func (r *MemoryRepository) AppendMessages(_ context.Context, id string, msgs ...ConversationMessage) error {
+	if err := r.lock(); err != nil {
+		return err
+	}
+	defer r.mu.Unlock()
+
+	c, ok := r.state.Conversations[id]
+	if !ok {
+		return fmt.Errorf("conversation %q not found", id)
+	}
+
+	now := time.Now()
+	for _, m := range msgs {
+		if m.CreatedAt.IsZero() {
+			m.CreatedAt = now
+		}
+		r.state.Messages[id] = append(r.state.Messages[id], m)
+	}
+	c.UpdatedAt = now
+
+	return r.persist(partConversations)
+}
+
+// -------- ProviderRegistry --------
+
+func (r *MemoryRepository) ListProviders(context.Context) ([]ProviderInfo, error) {
+	if err := r.rlock(); err != nil {
+		return nil, err
+	}
+	defer r.mu.RUnlock()
+
+	out := make([]ProviderInfo, len(r.state.Providers))
+	copy(out, r.state.Providers)
+	return out, nil
+}
+
+func (r *MemoryRepository) UpsertProvider(_ context.Context, p ProviderInfo) error {
+	if err := r.lock(); err != nil {
+		return err
+	}
+	defer r.mu.Unlock()
+
+	p.Aliases = normalizeTags(p.Aliases)
+	p.AmbiguousAliases = normalizeTags(p.AmbiguousAliases)
+	p.DocURLs = nonNil(p.DocURLs)
+	replaced := false
+	for i := range r.state.Providers {
+		if r.state.Providers[i].Slug == p.Slug {
+			r.state.Providers[i] = p
+			replaced = true
+		}
+	}
+	if !replaced {
+		r.state.Providers = append(r.state.Providers, p)
+		sort.Slice(r.state.Providers, func(a, b int) bool { return r.state.Providers[a].Slug < r.state.Providers[b].Slug })
+	}
+	return r.persist(partProviders)
+}
+
+// newUUID gera um UUID v4, no mesmo formato do gen_random_uuid() do Postgres.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// persist regrava o arquivo da parte (escreve num temporário e renomeia, para não
+// deixar o arquivo pela metade); as outras partes ficam intactas. Partes ainda no
+// arquivo único antigo ganham o seu arquivo na mesma hora, antes que a regravação dos
+// documentos as tire de lá. Chamar com o lock de escrita.
+func (r *MemoryRepository) persist(part memoryPart) error {
+	if r.path == "" {
+		return nil
+	}
+
+	unlock, err := lockFile(r.path + ".lock")
+	if err != nil {
+		return fmt.Errorf("persist memory store: %w", err)
+	}
+	defer unlock()
+
+	for _, p := range memoryParts {
+		if p != part && r.legacy&p == 0 {
+			continue
+		}
+		if p != part {
+			// outro processo já migrou a parte: vale o arquivo dele
+			if _, err := os.Stat(r.partPath(p)); err == nil {
+				continue
+			}
+		}
+		if err := r.writePart(p); err != nil {
+			return fmt.Errorf("persist memory store: %w", err)
+		}
+	}
+	r.legacy = 0
+	return nil
+}
+
+func (r *MemoryRepository) writePart(part memoryPart) error {
+	data, err := r.state.encodePart(part)
+	if err != nil {
+		return err
+	}
+
+	path := r.partPath(part)
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op depois do Rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	r.disk[part] = info
+	return nil
+}
+
+// staleLock é a idade a partir da qual o .lock é de um processo que morreu gravando.
+const staleLock = 30 * time.Second
+
+// lockFile trava o store entre processos criando path com O_EXCL; unlock apaga o arquivo.
+func lockFile(path string) (unlock func(), err error) {
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("lock %s still held after 10s", path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+var _ Repository = (*MemoryRepository)(nil)
+var _ DocumentRepository = (*MemoryRepository)(nil)
+var _ ConversationStore = (*MemoryRepository)(nil)
+var _ ProviderRegistry = (*MemoryRepository)(nil)
+var _ EndpointCatalog = (*MemoryRepository)(nil)
+var _ ErrorCodeCatalog = (*MemoryRepository)(nil)
@@ -0,0 +1,263 @@
+package rag_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+)
+
+func TestMemoryRepositorySearchMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	// a tem o mesmo ângulo da query mas norma maior; b é mais perto em L2
+	want := map[rag.DistanceMetric]string{rag.MetricCosine: "a", rag.MetricL2: "b"}
+
+	for metric, first := range want {
+		repo, err := rag.NewMemoryRepository("", metric)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = repo.InsertChunk(ctx, &rag.DocChunk{Provider: rag.ProviderRede, Title: "a"}, []float32{10, 0})
+		_, _ = repo.InsertChunk(ctx, &rag.DocChunk{Provider: rag.ProviderRede, Title: "b"}, []float32{1, 0.3})
+		_, _ = repo.InsertChunk(ctx, &rag.DocChunk{Provider: rag.ProviderEntrepay, Title: "c"}, []float32{1, 0})
+
+		got, err := repo.SearchSimilarChunks(ctx, rag.ProviderRede, []float32{1, 0}, 5, rag.SearchFilters{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("%s: got %d chunks, want 2 (provider filter)", metric, len(got))
+		}
+		if got[0].Title != first {
+			t.Errorf("%s: first = %q, want %q", metric, got[0].Title, first)
+		}
+	}
+}
+
+func TestMemoryRepositoryDocumentsAndPersistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store.json")
+
+	repo, err := rag.NewMemoryRepository(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := &rag.Document{Provider: rag.ProviderRede, Source: "docs/rede/a.md", ContentHash: "h1"}
+	chunks := []rag.DocChunk{{Provider: rag.ProviderRede, Title: "v1"}, {Provider: rag.ProviderRede, Title: "v1 parte 2"}}
+	if _, err := repo.ReplaceDocument(ctx, doc, chunks, [][]float32{{1, 0}, {0, 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// reimport troca os chunks do documento
+	doc.ContentHash = "h2"
+	if _, err := repo.ReplaceDocument(ctx, doc, []rag.DocChunk{{Provider: rag.ProviderRede, Title: "v2"}}, [][]float32{{1, 0}}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := rag.NewMemoryRepository(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := reopened.GetDocument(ctx, rag.ProviderRede, "docs/rede/a.md")
+	if err != nil || got == nil || got.ContentHash != "h2" {
+		t.Fatalf("GetDocument = %+v, %v", got, err)
+	}
+	found, _ := reopened.SearchSimilarChunks(ctx, rag.ProviderRede, []float32{1, 0}, 10, rag.SearchFilters{})
+	if len(found) != 1 || found[0].Title != "v2" {
+		t.Fatalf("chunks after replace = %+v", found)
+	}
+
+	n, err := reopened.PruneDocuments(ctx, rag.ProviderRede, "docs/rede/", nil)
+	if err != nil || n != 1 {
+		t.Fatalf("PruneDocuments = %d, %v", n, err)
+	}
+	found, _ = reopened.SearchSimilarChunks(ctx, rag.ProviderRede, []float32{1, 0}, 10, rag.SearchFilters{})
+	if len(found) != 0 {
+		t.Fatalf("chunks after prune = %+v", found)
+	}
+}
+
+func TestMemoryRepositorySharedFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store.json")
+
+	// a API sobe antes do import e fica com o arquivo vazio em memória
+	api, err := rag.NewMemoryRepository(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	importer, err := rag.NewMemoryRepository(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := &rag.Document{Provider: rag.ProviderRede, Source: "docs/a.md", ContentHash: "h1"}
+	if _, err := importer.ReplaceDocument(ctx, doc, []rag.DocChunk{{Provider: rag.ProviderRede, Title: "a"}}, [][]float32{{1, 0}}); err != nil {
+		t.Fatal(err)
+	}
+	// o /ask cria conversa depois do import: não pode regravar o arquivo sem o chunk
+	conv, err := api.CreateConversation(ctx, rag.ProviderRede)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// e o import seguinte não pode apagar a conversa
+	doc2 := &rag.Document{Provider: rag.ProviderRede, Source: "docs/b.md", ContentHash: "h2"}
+	if _, err := importer.ReplaceDocument(ctx, doc2, []rag.DocChunk{{Provider: rag.ProviderRede, Title: "b"}}, [][]float32{{0, 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := rag.NewMemoryRepository(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, _ := reopened.SearchSimilarChunks(ctx, rag.ProviderRede, []float32{1, 0}, 10, rag.SearchFilters{})
+	if len(found) != 2 {
+		t.Errorf("chunks = %+v, want a and b", found)
+	}
+	if c, _ := reopened.GetConversation(ctx, conv); c == nil {
+		t.Errorf("conversation %s lost", conv)
+	}
+	// a API enxerga os chunks importados depois que ela subiu, sem precisar gravar nada
+	if found, _ := api.SearchSimilarChunks(ctx, rag.ProviderRede, []float32{1, 0}, 10, rag.SearchFilters{}); len(found) != 2 {
+		t.Errorf("api chunks = %+v, want a and b", found)
+	}
+
+	// gravar mensagens não regrava o arquivo dos documentos (com os embeddings)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := api.AppendMessages(ctx, conv, rag.ConversationMessage{Role: rag.RoleUser, Content: "oi"}); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := os.ReadFile(path)
+	if string(before) != string(after) || strings.Contains(string(after), conv) {
+		t.Error("conversation written to the documents file")
+	}
+	if msgs, _ := reopened.ListMessages(ctx, conv, 0); len(msgs) != 1 {
+		t.Errorf("messages seen by the other process = %+v", msgs)
+	}
+}
+
+// O arquivo único antigo (tudo em path) continua sendo lido; a primeira gravação
+// separa as conversas e os providers nos seus arquivos.
+func TestMemoryRepositoryLegacyFile(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "store.json")
+	legacy := `{"nextChunkId":1,"nextDocId":0,"chunks":[{"chunk":{"id":1,"provider":"rede","title":"a"},"embedding":[1,0]}],` +
+		`"conversations":{"c1":{"id":"c1","provider":"rede"}},"messages":{"c1":[{"role":"user","content":"oi"}]},` +
+		`"providers":[{"slug":"rede","displayName":"e-Rede","aliases":[],"docUrls":[],"enabled":true}]}`
+	if err := os.WriteFile(path, []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := rag.NewMemoryRepository(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := repo.GetConversation(ctx, "c1"); c == nil {
+		t.Fatal("legacy conversation not loaded")
+	}
+	if ps, _ := repo.ListProviders(ctx); len(ps) != 1 {
+		t.Fatalf("providers = %+v, want the legacy one", ps)
+	}
+
+	// o importador regrava os documentos: as conversas saem de path mas vão para o arquivo delas
+	if _, err := repo.ReplaceDocument(ctx, &rag.Document{Provider: rag.ProviderRede, Source: "docs/b.md"}, []rag.DocChunk{{Provider: rag.ProviderRede, Title: "b"}}, [][]float32{{0, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"store.conversations.json", "store.providers.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not created: %v", name, err)
+		}
+	}
+
+	reopened, err := rag.NewMemoryRepository(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgs, _ := reopened.ListMessages(ctx, "c1", 0); len(msgs) != 1 {
+		t.Errorf("messages = %+v", msgs)
+	}
+	if ps, _ := reopened.ListProviders(ctx); len(ps) != 1 {
+		t.Errorf("providers = %+v", ps)
+	}
+	if found, _ := reopened.SearchSimilarChunks(ctx, rag.ProviderRede, []float32{1, 0}, 10, rag.SearchFilters{}); len(found) != 2 {
+		t.Errorf("chunks = %+v", found)
+	}
+}
+
+func TestMemoryRepositoryPruneMatchesWholeSegments(t *testing.T) {
+	ctx := context.Background()
+	repo, err := rag.NewMemoryRepository("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range []string{"docs/a.md", "docs/sub/b.md", "docs-old/c.md", "https://dev.x/e-rede/p", "https://dev.x/e-rede-v2/p"} {
+		if _, err := repo.ReplaceDocument(ctx, &rag.Document{Provider: rag.ProviderRede, Source: src}, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// "docs" não pega o diretório vizinho docs-old
+	if n, err := repo.PruneDocuments(ctx, rag.ProviderRede, "docs", []string{"docs/a.md"}); err != nil || n != 1 {
+		t.Fatalf("prune docs = %d, %v", n, err)
+	}
+	// base URL com barra no fim: não pega .../e-rede-v2
+	if n, err := repo.PruneDocuments(ctx, rag.ProviderRede, "https://dev.x/e-rede/", nil); err != nil || n != 1 {
+		t.Fatalf("prune url = %d, %v", n, err)
+	}
+	for src, want := range map[string]bool{"docs/a.md": true, "docs/sub/b.md": false, "docs-old/c.md": true, "https://dev.x/e-rede/p": false, "https://dev.x/e-rede-v2/p": true} {
+		if d, _ := repo.GetDocument(ctx, rag.ProviderRede, src); (d != nil) != want {
+			t.Errorf("%s kept = %v, want %v", src, d != nil, want)
+		}
+	}
+}
+
+func TestMemoryRepositoryFilters(t *testing.T) {
+	ctx := context.Background()
+	repo, err := rag.NewMemoryRepository("", rag.MetricCosine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = repo.InsertChunk(ctx, &rag.DocChunk{Provider: rag.ProviderRede, Title: "auth", SectionType: rag.SectionAuth, Tags: []string{"oauth"}, APIVersion: "v1"}, []float32{1, 0})
+	_, _ = repo.InsertChunk(ctx, &rag.DocChunk{Provider: rag.ProviderRede, Title: "errors v1", SectionType: rag.SectionErrors, Tags: []string{"errors", "refund"}, APIVersion: "v1"}, []float32{1, 0.1})
+	_, _ = repo.InsertChunk(ctx, &rag.DocChunk{Provider: rag.ProviderRede, Title: "errors v2", SectionType: rag.SectionErrors, Tags: []string{"errors"}, APIVersion: "v2"}, []float32{1, 0.2})
+
+	cases := []struct {
+		name    string
+		filters rag.SearchFilters
+		want    []string
+	}{
+		{"none", rag.SearchFilters{}, []string{"auth", "errors v1", "errors v2"}},
+		{"section", rag.SearchFilters{SectionTypes: []rag.SectionType{rag.SectionErrors}}, []string{"errors v1", "errors v2"}},
+		{"version", rag.SearchFilters{SectionTypes: []rag.SectionType{rag.SectionErrors}, APIVersion: "v2"}, []string{"errors v2"}},
+		{"tagsAll", rag.SearchFilters{TagsAll: []string{"Errors", "refund"}}, []string{"errors v1"}},
+		{"tagsAny", rag.SearchFilters{TagsAny: []string{"oauth", "refund"}}, []string{"auth", "errors v1"}},
+	}
+	for _, tc := range cases {
+		got, err := repo.SearchHybridChunks(ctx, rag.ProviderRede, "errors", []float32{1, 0}, 10, tc.filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		titles := make(map[string]bool)
+		for _, c := range got {
+			titles[c.Title] = true
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: got %d chunks, want %v", tc.name, len(got), tc.want)
+			continue
+		}
+		for _, w := range tc.want {
+			if !titles[w] {
+				t.Errorf("%s: missing %q", tc.name, w)
+			}
+		}
+	}
+}
@@ -6,12 +6,31 @@ import "time"
 // Já deixo tipado p/ evitar string solta no código.
 type Provider string
 
+// Gateways que vêm cadastrados por padrão (migration 005). Outros entram na tabela
+// provider, sem mudar código.
 const (
 	ProviderRede     Provider = "rede"
 	ProviderEntrepay Provider = "entrepay"
-	// Futuro: ProviderCielo, ProviderStripe...
 )
 
+// ProviderAll em AskRequest.Providers compara todos os providers habilitados.
+const ProviderAll Provider = "all"
+
+// ProviderInfo
+// Cadastro de um gateway: slug usado em provider/doc_chunk, apelidos que o identificam
+// numa pergunta, URLs base da documentação e a api_version padrão do import.
+// AmbiguousAliases são apelidos que também são palavras comuns ("rede"): sozinhos
+// não bastam para escolher o provider.
+type ProviderInfo struct {
+	Slug              Provider `json:"slug"`
+	DisplayName       string   `json:"displayName"`
+	Aliases           []string `json:"aliases"`
+	AmbiguousAliases  []string `json:"ambiguousAliases,omitempty"`
+	DocURLs           []string `json:"docUrls"`
+	DefaultAPIVersion string   `json:"defaultApiVersion,omitempty"`
+	Enabled           bool     `json:"enabled"`
+}
+
 type SectionType string
 
 const (
@@ -22,6 +41,24 @@ const (
 	SectionErrors   SectionType = "errors"
 )
 
+// SearchMode define como os chunks são recuperados.
+// Vector é o comportamento original (só pgvector); Hybrid junta full-text + vetor via RRF.
+type SearchMode string
+
+const (
+	SearchVector SearchMode = "vector"
+	SearchHybrid SearchMode = "hybrid"
+)
+
+// SearchFilters restringe a busca pelos metadados do chunk. Campos vazios não filtram.
+// TagsAll exige todas as tags; TagsAny exige pelo menos uma.
+type SearchFilters struct {
+	SectionTypes []SectionType `json:"sectionTypes,omitempty"`
+	TagsAll      []string      `json:"tagsAll,omitempty"`
+	TagsAny      []string      `json:"tagsAny,omitempty"`
+	APIVersion   string        `json:"apiVersion,omitempty"`
+}
+
 // DocChunk
 // Um pedaço lógico da documentação (um endpoint, uma seção 3DS, uma tabela de códigos etc).
 type DocChunk struct {
@@ -35,6 +72,19 @@ type DocChunk struct {
 	Tags        []string    `json:"tags"`
 	CreatedAt   time.Time   `json:"createdAt"`
 	UpdatedAt   time.Time   `json:"updatedAt"`
+
+	// Preenchidos pela busca: distância L2 do embedding da pergunta e similaridade de cosseno.
+	// LexicalMatch indica que o chunk também casou no full-text (modo híbrido).
+	Distance     float64 `json:"distance,omitempty"`
+	Similarity   float64 `json:"similarity,omitempty"`
+	LexicalMatch bool    `json:"lexicalMatch,omitempty"`
+
+	// Score é a pontuação final de ranking (preenchida pelo Reranker, quando houver).
+	Score float64 `json:"score,omitempty"`
+
+	// Endpoints e códigos de erro achados no conteúdo pelo importador; gravados junto com o chunk.
+	Endpoints  []Endpoint  `json:"-"`
+	ErrorCodes []ErrorCode `json:"-"`
 }
 
 // DocChunkEmbedding
@@ -45,28 +95,202 @@ type DocChunkEmbedding struct {
 	CreatedAt time.Time `json:"createdAt"`
 }
 
+// Document
+// Arquivo ou página de onde saíram os chunks; usado para reimportar sem duplicar.
+type Document struct {
+	ID          int64     `json:"id"`
+	Provider    Provider  `json:"provider"`
+	Source      string    `json:"source"` // caminho do arquivo ou URL
+	ContentHash string    `json:"contentHash"`
+	APIVersion  string    `json:"apiVersion"`
+	ImportedAt  time.Time `json:"importedAt"`
+}
+
 // AskRequest
 // Payload da sua API /ask.
 type AskRequest struct {
-	Question string    `json:"question"`
-	Provider *Provider `json:"provider,omitempty"` // opcional; se vazio, você detecta pelo texto
-	TopK     int       `json:"topK,omitempty"`     // opcional; default interno
-	Lang     string      `json:"lang"`
+	Question   string     `json:"question"`
+	Provider   *Provider  `json:"provider,omitempty"` // opcional; se vazio, você detecta pelo texto
+	TopK       int        `json:"topK,omitempty"`     // opcional; default interno
+	Lang       string     `json:"lang"`
+	SearchMode SearchMode `json:"searchMode,omitempty"` // opcional; "vector" (default) ou "hybrid"
+
+	// MinSimilarity sobrescreve o corte mínimo de similaridade configurado no serviço.
+	MinSimilarity *float64 `json:"minSimilarity,omitempty"`
+
+	// ConversationID continua uma conversa existente; vazio começa uma nova.
+	ConversationID string `json:"conversationId,omitempty"`
+
+	// Filters restringe a busca por section_type, tags e api_version.
+	Filters SearchFilters `json:"filters,omitempty"`
+
+	// Providers liga o modo comparação: a busca roda em cada provider (ou em todos, com
+	// "all") e a resposta compara lado a lado. Perguntas que citam mais de um gateway
+	// entram nesse modo sozinhas.
+	Providers []Provider `json:"providers,omitempty"`
 }
 
 // SourceRef
 // Metadados dos trechos usados para montar a resposta.
+// Ref é o número do trecho no prompt, o mesmo das marcações [n] da resposta.
 type SourceRef struct {
-	ChunkID   int64    `json:"chunkId"`
-	Title     string   `json:"title"`
-	Provider  Provider `json:"provider"`
-	SourceURL string   `json:"sourceUrl"`
+	Ref        int      `json:"ref"`
+	ChunkID    int64    `json:"chunkId"`
+	Title      string   `json:"title"`
+	Provider   Provider `json:"provider"`
+	SourceURL  string   `json:"sourceUrl"`
+	Distance   float64  `json:"distance"`
+	Similarity float64  `json:"similarity"`
+	Score      float64  `json:"score,omitempty"` // score do reranker; explica por que o chunk foi escolhido
+}
+
+// Citation
+// Um trecho da resposta sustentado por uma ou mais fontes (marcação [n] logo depois dele).
+// Start/End são posições em caracteres (runes) dentro de AskResponse.Answer.
+type Citation struct {
+	Text     string  `json:"text"`
+	Start    int     `json:"start"`
+	End      int     `json:"end"`
+	Refs     []int   `json:"refs"`
+	ChunkIDs []int64 `json:"chunkIds"`
+}
+
+// Warning
+// Item da resposta (endpoint, URL, campo, código) que não aparece nos trechos recuperados:
+// provável invenção do modelo.
+type Warning struct {
+	Kind    WarningKind `json:"kind"`
+	Value   string      `json:"value"`
+	Message string      `json:"message"`
 }
 
 // AskResponse
 // Resposta da API: texto + fontes.
+// Sources traz só os trechos citados na resposta (ou todos, se o modelo não citou nenhum).
 type AskResponse struct {
-	Answer   string      `json:"answer"`
-	Provider Provider    `json:"provider"`
-	Sources  []SourceRef `json:"sources"`
+	Answer         string      `json:"answer"`
+	Provider       Provider    `json:"provider"`
+	Sources        []SourceRef `json:"sources"`
+	Citations      []Citation  `json:"citations"`
+	Warnings       []Warning   `json:"warnings,omitempty"` // só com a verificação de grounding ligada
+	ConversationID string      `json:"conversationId,omitempty"`
+	Intent         *Intent     `json:"intent,omitempty"` // como a pergunta foi classificada
+
+	// Só no modo comparação: Provider fica vazio, Providers lista os comparados e
+	// SourcesByProvider agrupa as mesmas fontes de Sources.
+	Providers         []Provider               `json:"providers,omitempty"`
+	SourcesByProvider map[Provider][]SourceRef `json:"sourcesByProvider,omitempty"`
+
+	// ProviderDetection explica a escolha do provider. Com ambiguous=true a pergunta não
+	// identificou o gateway e Answer pede esclarecimento (sem busca nem LLM).
+	ProviderDetection *ProviderDetection `json:"providerDetection,omitempty"`
+
+	// Endpoints do catálogo que respondem a pergunta, quando ela procura um endpoint
+	// ("qual a rota de estorno?"). Determinístico: cada um cita o chunk de onde saiu.
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
+
+	// ErrorCode vem preenchido quando a resposta saiu da tabela de códigos de erro
+	// (pergunta sobre um código cadastrado), sem passar pelo LLM.
+	ErrorCode *ErrorCode `json:"errorCode,omitempty"`
+}
+
+// SearchRequest
+// Payload do /search: só recuperação, sem geração de resposta.
+type SearchRequest struct {
+	Query         string     `json:"query"`
+	Provider      *Provider  `json:"provider,omitempty"`
+	TopK          int        `json:"topK,omitempty"`
+	SearchMode    SearchMode `json:"searchMode,omitempty"`
+	MinSimilarity *float64   `json:"minSimilarity,omitempty"`
+
+	Filters   SearchFilters `json:"filters,omitempty"`
+	Providers []Provider    `json:"providers,omitempty"` // busca em vários providers (ou "all")
+}
+
+// SearchResult
+// Um chunk encontrado, com os scores da busca e um trecho com os termos em <mark>.
+type SearchResult struct {
+	DocChunk
+	Snippet string `json:"snippet"`
+}
+
+// SearchResponse
+// Resposta do /search.
+type SearchResponse struct {
+	Query     string         `json:"query"`
+	Provider  Provider       `json:"provider"`
+	Providers []Provider     `json:"providers,omitempty"`
+	Intent    *Intent        `json:"intent,omitempty"`
+	Results   []SearchResult `json:"results"`
+
+	ProviderDetection *ProviderDetection `json:"providerDetection,omitempty"`
+}
+
+// MessageRole é quem falou numa conversa.
+type MessageRole string
+
+const (
+	RoleUser      MessageRole = "user"
+	RoleAssistant MessageRole = "assistant"
+)
+
+// Conversation
+// Sessão multi-turn do /ask. Provider é o da primeira pergunta, usado
+// quando um follow-up não menciona gateway nenhum.
+type Conversation struct {
+	ID        string    `json:"id"`
+	Provider  Provider  `json:"provider"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ConversationMessage
+// Um turno da conversa (pergunta do usuário ou resposta do assistente).
+type ConversationMessage struct {
+	Role      MessageRole `json:"role"`
+	Content   string      `json:"content"`
+	CreatedAt time.Time   `json:"createdAt"`
+}
+
+// Usage
+// Consumo de tokens reportado pelo provider do LLM.
+type Usage struct {
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+	TotalTokens      int `json:"totalTokens"`
+}
+
+// StreamEventType é o nome do evento SSE em /ask/stream.
+type StreamEventType string
+
+const (
+	StreamSources StreamEventType = "sources"
+	StreamToken   StreamEventType = "token"
+	StreamDone    StreamEventType = "done"
+	StreamError   StreamEventType = "error"
+)
+
+// StreamEvent
+// Um evento do /ask/stream: primeiro as fontes, depois os pedaços da resposta,
+// por fim o "done" com o uso de tokens. O evento "sources" traz também a intenção classificada,
+// como o provider foi detectado e os endpoints do catálogo (perguntas sobre endpoint).
+// No "done", Answer é o texto final com as citações validadas (substitui o acumulado
+// dos tokens), Sources são só as fontes citadas, Citations os trechos citados e
+// Warnings o resultado da verificação de grounding (no stream não há regeneração).
+type StreamEvent struct {
+	Type              StreamEventType    `json:"-"`
+	Provider          Provider           `json:"provider,omitempty"`
+	Providers         []Provider         `json:"providers,omitempty"` // modo comparação
+	Sources           []SourceRef        `json:"sources,omitempty"`
+	ConversationID    string             `json:"conversationId,omitempty"`
+	Intent            *Intent            `json:"intent,omitempty"`
+	ProviderDetection *ProviderDetection `json:"providerDetection,omitempty"`
+	Endpoints         []Endpoint         `json:"endpoints,omitempty"`
+	ErrorCode         *ErrorCode         `json:"errorCode,omitempty"` // resposta da tabela de códigos
+	Token             string             `json:"token,omitempty"`
+	Answer            string             `json:"answer,omitempty"`
+	Citations         []Citation         `json:"citations,omitempty"`
+	Warnings          []Warning          `json:"warnings,omitempty"`
+	Usage             *Usage             `json:"usage,omitempty"`
+	Error             string             `json:"error,omitempty"`
 }
@@ -0,0 +1,220 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ProviderRegistry é o cadastro de gateways (tabela provider).
+type ProviderRegistry interface {
+	// ListProviders devolve todos os providers, inclusive os desabilitados, ordenados pelo slug.
+	ListProviders(ctx context.Context) ([]ProviderInfo, error)
+	UpsertProvider(ctx context.Context, p ProviderInfo) error
+}
+
+// DefaultProviders é o cadastro inicial, o mesmo das migrations 005 e 006.
+func DefaultProviders() []ProviderInfo {
+	return []ProviderInfo{
+		{
+			Slug:        ProviderEntrepay,
+			DisplayName: "Entrepay",
+			Aliases:     []string{"entrepay"},
+			DocURLs:     []string{},
+			Enabled:     true,
+		},
+		{
+			Slug:        ProviderRede,
+			DisplayName: "e-Rede",
+			Aliases:     []string{"rede", "e-rede", "erede", "e rede", "userede"},
+			DocURLs:     []string{"https://developer.userede.com.br/e-rede"},
+			Enabled:     true,
+
+			AmbiguousAliases: []string{"rede", "e rede"}, // "internet e rede" também casa com "e rede"
+		},
+	}
+}
+
+// staticProviders é o registry usado quando o Service não recebe WithProviders.
+type staticProviders []ProviderInfo
+
+func (p staticProviders) ListProviders(context.Context) ([]ProviderInfo, error) {
+	return p, nil
+}
+
+func (p staticProviders) UpsertProvider(context.Context, ProviderInfo) error {
+	return fmt.Errorf("static provider registry is read-only")
+}
+
+func (r *PgRepository) ListProviders(ctx context.Context) ([]ProviderInfo, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT slug, display_name, aliases, ambiguous_aliases, doc_urls, COALESCE(default_api_version, ''), enabled
+		FROM provider
+		ORDER BY slug
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ProviderInfo
+	for rows.Next() {
+		var p ProviderInfo
+		if err := rows.Scan(&p.Slug, &p.DisplayName, &p.Aliases, &p.AmbiguousAliases, &p.DocURLs, &p.DefaultAPIVersion, &p.Enabled); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (r *PgRepository) UpsertProvider(ctx context.Context, p ProviderInfo) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO provider (slug, display_name, aliases, ambiguous_aliases, doc_urls, default_api_version, enabled)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+		ON CONFLICT (slug) DO UPDATE SET
+			display_name = EXCLUDED.display_name,
+			aliases = EXCLUDED.aliases,
+			ambiguous_aliases = EXCLUDED.ambiguous_aliases,
+			doc_urls = EXCLUDED.doc_urls,
+			default_api_version = EXCLUDED.default_api_version,
+			enabled = EXCLUDED.enabled,
+			updated_at = NOW()
+	`, p.Slug, p.DisplayName, normalizeTags(p.Aliases), normalizeTags(p.AmbiguousAliases), nonNil(p.DocURLs), p.DefaultAPIVersion, p.Enabled)
+	return err
+}
+
+var _ ProviderRegistry = (*PgRepository)(nil)
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// enabledProviders filtra os providers habilitados.
+func enabledProviders(all []ProviderInfo) []ProviderInfo {
+	var out []ProviderInfo
+	for _, p := range all {
+		if p.Enabled {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// LookupProvider acha o provider pelo slug ou por um dos apelidos (sem caixa).
+func LookupProvider(providers []ProviderInfo, name string) *ProviderInfo {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i, p := range providers {
+		if string(p.Slug) == name {
+			return &providers[i]
+		}
+	}
+	for i, p := range providers {
+		for _, a := range p.Aliases {
+			if strings.ToLower(a) == name {
+				return &providers[i]
+			}
+		}
+	}
+	return nil
+}
+
+// detectProvider procura os apelidos na pergunta como palavra inteira ("rede" não casa
+// com "redefinir"). Vale o que aparece primeiro.
+func detectProvider(providers []ProviderInfo, question string) Provider {
+	if found := detectProviders(providers, question); len(found) > 0 {
+		return found[0]
+	}
+	return ""
+}
+
+// detectProviders devolve os providers citados na pergunta por um apelido não ambíguo,
+// na ordem em que aparecem.
+func detectProviders(providers []ProviderInfo, question string) []Provider {
+	strong, _, _ := matchProviders(providers, question)
+	return strong
+}
+
+// matchProviders separa os providers citados na pergunta: strong por algum apelido
+// não ambíguo, weak só por apelidos ambíguos ("erro de rede" pode não ser a e-Rede);
+// all junta os dois. Cada lista vem na ordem em que os providers aparecem.
+func matchProviders(providers []ProviderInfo, question string) (strong, weak, all []Provider) {
+	type hit struct {
+		slug   Provider
+		pos    int
+		strong bool
+	}
+	var hits []hit
+	for _, p := range providers {
+		h := hit{slug: p.Slug, pos: -1}
+		for _, a := range append([]string{string(p.Slug)}, p.Aliases...) {
+			if a == "" {
+				continue
+			}
+			loc := aliasRe(a).FindStringIndex(question)
+			if loc == nil {
+				continue
+			}
+			if h.pos < 0 || loc[0] < h.pos {
+				h.pos = loc[0]
+			}
+			if !containsFold(p.AmbiguousAliases, a) {
+				h.strong = true
+			}
+		}
+		if h.pos >= 0 {
+			hits = append(hits, h)
+		}
+	}
+	sort.SliceStable(hits, func(a, b int) bool { return hits[a].pos < hits[b].pos })
+
+	for _, h := range hits {
+		if h.strong {
+			strong = append(strong, h.slug)
+		} else {
+			weak = append(weak, h.slug)
+		}
+		all = append(all, h.slug)
+	}
+	return strong, weak, all
+}
+
+func containsFold(list []string, s string) bool {
+	for _, x := range list {
+		if strings.EqualFold(x, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// aliasRes guarda o regexp de cada apelido já visto: o cadastro é relido a cada pergunta,
+// mas os apelidos quase nunca mudam, então cada um é compilado uma vez só.
+var aliasRes sync.Map // string → *regexp.Regexp
+
+func aliasRe(alias string) *regexp.Regexp {
+	if re, ok := aliasRes.Load(alias); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}_-])` + regexp.QuoteMeta(alias) + `(?:$|[^\p{L}\p{N}_-])`)
+	aliasRes.Store(alias, re)
+	return re
+}
+
+// providerHint lista os slugs para as mensagens de erro ("'rede' ou 'entrepay'").
+func providerHint(providers []ProviderInfo) string {
+	var names []string
+	for _, p := range providers {
+		names = append(names, "'"+string(p.Slug)+"'")
+	}
+	if len(names) <= 1 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " ou " + names[len(names)-1]
+}
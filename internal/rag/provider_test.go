@@ -0,0 +1,85 @@
+package rag
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestResolveProvider(t *testing.T) {
+	providers := append(DefaultProviders(), ProviderInfo{Slug: "cielo", DisplayName: "Cielo", Aliases: []string{"cielo", "api cielo"}, Enabled: true})
+	p := func(s string) *Provider { v := Provider(s); return &v }
+
+	cases := []struct {
+		explicit *Provider
+		question string
+		want     Provider
+		wantErr  bool
+	}{
+		{nil, "como estornar na e-Rede?", ProviderRede, false},
+		{nil, "Entrepay tem 3DS?", ProviderEntrepay, false},
+		{nil, "captura na Cielo, diferente da rede", "cielo", false},
+		{nil, "como redefinir a senha?", "", false},                             // "rede" só como palavra inteira
+		{nil, "configurar a conexão de internet e rede do servidor", "", false}, // "e rede" é ambíguo
+		{p("e-rede"), "qualquer coisa", ProviderRede, false},
+		{p("stone"), "qualquer coisa", "", true},
+	}
+	for _, tc := range cases {
+		got, err := resolveProvider(providers, tc.explicit, tc.question)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("resolveProvider(%q) = %q, %v; want %q", tc.question, got, err, tc.want)
+		}
+	}
+}
+
+func TestMatchProvidersAmbiguousAliases(t *testing.T) {
+	cases := []struct {
+		question     string
+		strong, weak []Provider
+	}{
+		{"configurar a conexão de internet e rede do servidor", nil, []Provider{ProviderRede}},
+		{"erro de rede ao capturar", nil, []Provider{ProviderRede}},
+		{"como estornar na e-Rede?", []Provider{ProviderRede}, nil},
+		{"token da erede expirou", []Provider{ProviderRede}, nil},
+		{"Entrepay ou rede?", []Provider{ProviderEntrepay}, []Provider{ProviderRede}},
+	}
+	for _, tc := range cases {
+		strong, weak, _ := matchProviders(DefaultProviders(), tc.question)
+		if !reflect.DeepEqual(strong, tc.strong) || !reflect.DeepEqual(weak, tc.weak) {
+			t.Errorf("matchProviders(%q) = strong %v, weak %v; want %v, %v", tc.question, strong, weak, tc.strong, tc.weak)
+		}
+	}
+}
+
+func TestAliasRegexpIsCached(t *testing.T) {
+	if aliasRe("e-rede") != aliasRe("e-rede") {
+		t.Error("alias regexp compiled again on each call")
+	}
+	if !aliasRe("e-rede").MatchString("estorno na E-Rede?") || aliasRe("rede").MatchString("redefinir") {
+		t.Error("cached regexp does not match whole words")
+	}
+}
+
+func TestMemoryRepositoryProviders(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewMemoryRepository("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.UpsertProvider(ctx, ProviderInfo{Slug: "cielo", DisplayName: "Cielo", Aliases: []string{" Cielo "}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpsertProvider(ctx, ProviderInfo{Slug: ProviderEntrepay, DisplayName: "Entrepay", Aliases: []string{"entrepay"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	all, _ := repo.ListProviders(ctx)
+	if len(all) != 3 || all[0].Slug != "cielo" || all[0].Aliases[0] != "cielo" {
+		t.Fatalf("providers = %+v", all)
+	}
+	enabled := enabledProviders(all)
+	if len(enabled) != 1 || enabled[0].Slug != ProviderRede {
+		t.Fatalf("enabled = %+v, want only rede", enabled)
+	}
+}
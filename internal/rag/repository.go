@@ -2,17 +2,33 @@ package rag
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"unicode"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pgvector/pgvector-go"
 )
 
+// Repository é a busca de chunks. Provider vazio em SearchSimilarChunks busca em todos
+// os providers (usado para detectar o gateway de perguntas que não o citam).
 type Repository interface {
 	InsertChunk(ctx context.Context, c *DocChunk, embedding []float32) (int64, error)
 	GetChunksByIDs(ctx context.Context, ids []int64) ([]DocChunk, error)
-	SearchSimilarChunks(ctx context.Context, provider Provider, embedding []float32, limit int) ([]DocChunk, error)
+	SearchSimilarChunks(ctx context.Context, provider Provider, embedding []float32, limit int, filters SearchFilters) ([]DocChunk, error)
+	SearchHybridChunks(ctx context.Context, provider Provider, query string, embedding []float32, limit int, filters SearchFilters) ([]DocChunk, error)
 }
 
+// rrfK é a constante da reciprocal-rank fusion (score = Σ 1/(k + rank)).
+// 60 é o valor do paper original e funciona bem sem tuning.
+const rrfK = 60
+
+// hybridCandidates é quantos candidatos cada ranking (lexical e vetorial)
+// contribui para a fusão, em múltiplos do limit pedido.
+const hybridCandidates = 4
+
 type PgRepository struct {
 	db *pgxpool.Pool
 }
@@ -21,12 +37,22 @@ func NewPgRepository(db *pgxpool.Pool) *PgRepository {
 	return &PgRepository{db: db}
 }
 
+// dbtx é o que o pool e uma transação têm em comum, para reaproveitar os inserts.
+type dbtx interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
 func (r *PgRepository) InsertChunk(ctx context.Context, c *DocChunk, embedding []float32) (int64, error) {
+	return insertChunk(ctx, r.db, c, nil, embedding)
+}
+
+func insertChunk(ctx context.Context, db dbtx, c *DocChunk, documentID *int64, embedding []float32) (int64, error) {
 	var id int64
 
-	err := r.db.QueryRow(ctx, `
-		INSERT INTO doc_chunk (provider, section_type, title, content, source_url, api_version, tags)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	err := db.QueryRow(ctx, `
+		INSERT INTO doc_chunk (provider, section_type, title, content, source_url, api_version, tags, document_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id
 	`,
 		c.Provider,
@@ -36,6 +62,7 @@ func (r *PgRepository) InsertChunk(ctx context.Context, c *DocChunk, embedding [
 		c.SourceURL,
 		c.APIVersion,
 		c.Tags,
+		documentID,
 	).Scan(&id)
 	if err != nil {
 		return 0, err
@@ -43,7 +70,7 @@ func (r *PgRepository) InsertChunk(ctx context.Context, c *DocChunk, embedding [
 
 	if embedding != nil {
 		vec := pgvector.NewVector(embedding)
-		_, err = r.db.Exec(ctx, `
+		_, err = db.Exec(ctx, `
 			INSERT INTO doc_chunk_embedding (chunk_id, embedding)
 			VALUES ($1, $2)
 		`, id, vec)
@@ -52,6 +79,14 @@ func (r *PgRepository) InsertChunk(ctx context.Context, c *DocChunk, embedding [
 		}
 	}
 
+	c.ID = id
+	if err := insertEndpoints(ctx, db, c); err != nil {
+		return 0, err
+	}
+	if err := insertErrorCodes(ctx, db, c); err != nil {
+		return 0, err
+	}
+
 	return id, nil
 }
 
@@ -64,33 +99,43 @@ func (r *PgRepository) GetChunksByIDs(ctx context.Context, ids []int64) ([]DocCh
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var chunks []DocChunk
-	for rows.Next() {
-		var c DocChunk
-		if err := rows.Scan(
-			&c.ID,
-			&c.Provider,
-			&c.SectionType,
-			&c.Title,
-			&c.Content,
-			&c.SourceURL,
-			&c.APIVersion,
-			&c.Tags,
-			&c.CreatedAt,
-			&c.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		chunks = append(chunks, c)
+	return scanChunks(rows, nil)
+}
+
+// filterSQL gera as condições dos SearchFilters usando os parâmetros $n..$n+3
+// (na ordem de filterArgs). Filtro vazio vira array vazio / string vazia e não restringe.
+func filterSQL(n int) string {
+	return fmt.Sprintf(`
+			  AND (cardinality($%[1]d::text[]) = 0 OR c.section_type = ANY($%[1]d::text[]))
+			  AND (cardinality($%[2]d::text[]) = 0 OR c.tags @> $%[2]d::text[])
+			  AND (cardinality($%[3]d::text[]) = 0 OR c.tags && $%[3]d::text[])
+			  AND ($%[4]d::text = '' OR c.api_version = $%[4]d::text)`, n, n+1, n+2, n+3)
+}
+
+func filterArgs(f SearchFilters) []any {
+	sections := make([]string, 0, len(f.SectionTypes))
+	for _, st := range f.SectionTypes {
+		sections = append(sections, string(st))
 	}
+	return []any{sections, normalizeTags(f.TagsAll), normalizeTags(f.TagsAny), f.APIVersion}
+}
 
-	return chunks, rows.Err()
+// normalizeTags deixa as tags do filtro no formato gravado pelo importador (minúsculas)
+// e nunca devolve nil (o Postgres trataria como NULL, não como "sem filtro").
+func normalizeTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
 }
 
-// SearchSimilarChunks faz a busca vetorial filtrando por provider.
-func (r *PgRepository) SearchSimilarChunks(ctx context.Context, provider Provider, embedding []float32, limit int) ([]DocChunk, error) {
+// SearchSimilarChunks faz a busca vetorial filtrando por provider (vazio = todos) e pelos
+// SearchFilters. Cada chunk volta com Distance (L2, a mesma da ordenação) e Similarity (cosseno).
+func (r *PgRepository) SearchSimilarChunks(ctx context.Context, provider Provider, embedding []float32, limit int, filters SearchFilters) ([]DocChunk, error) {
 	if limit <= 0 {
 		limit = 5
 	}
@@ -100,22 +145,98 @@ func (r *PgRepository) SearchSimilarChunks(ctx context.Context, provider Provide
 	rows, err := r.db.Query(ctx, `
 		SELECT 
 			c.id, c.provider, c.section_type, c.title, c.content,
-			c.source_url, c.api_version, c.tags, c.created_at, c.updated_at
+			c.source_url, c.api_version, c.tags, c.created_at, c.updated_at,
+			e.embedding <-> $2 AS distance,
+			1 - (e.embedding <=> $2) AS similarity
 		FROM doc_chunk c
 		JOIN doc_chunk_embedding e ON c.id = e.chunk_id
-		WHERE c.provider = $1
+		WHERE ($1::text = '' OR c.provider = $1::text)`+filterSQL(4)+`
 		ORDER BY e.embedding <-> $2
 		LIMIT $3
-	`, provider, vec, limit)
+	`, append([]any{provider, vec, limit}, filterArgs(filters)...)...)
 	if err != nil {
 		return nil, err
 	}
+
+	return scanChunks(rows, func(c *DocChunk) []any {
+		return []any{&c.Distance, &c.Similarity}
+	})
+}
+
+// SearchHybridChunks combina a busca full-text (tsvector em portuguese + english)
+// com a busca vetorial, fundindo os dois rankings via reciprocal-rank fusion.
+// Serve para tokens exatos que o embedding sozinho perde (códigos de erro, nomes de campos).
+func (r *PgRepository) SearchHybridChunks(ctx context.Context, provider Provider, query string, embedding []float32, limit int, filters SearchFilters) ([]DocChunk, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+
+	lexical := lexicalQuery(query)
+	if lexical == "" {
+		return r.SearchSimilarChunks(ctx, provider, embedding, limit, filters)
+	}
+
+	vec := pgvector.NewVector(embedding)
+
+	rows, err := r.db.Query(ctx, `
+		WITH q AS (
+			SELECT websearch_to_tsquery('portuguese', $3) || websearch_to_tsquery('english', $3) AS query
+		),
+		vector_rank AS (
+			SELECT c.id, ROW_NUMBER() OVER (ORDER BY e.embedding <-> $2) AS rnk
+			FROM doc_chunk c
+			JOIN doc_chunk_embedding e ON c.id = e.chunk_id
+			WHERE c.provider = $1`+filterSQL(7)+`
+			ORDER BY e.embedding <-> $2
+			LIMIT $4
+		),
+		lexical_rank AS (
+			SELECT c.id, ROW_NUMBER() OVER (ORDER BY ts_rank_cd(c.search_tsv, q.query) DESC) AS rnk
+			FROM doc_chunk c, q
+			WHERE c.provider = $1
+			  AND c.search_tsv @@ q.query`+filterSQL(7)+`
+			ORDER BY ts_rank_cd(c.search_tsv, q.query) DESC
+			LIMIT $4
+		),
+		fused AS (
+			SELECT id, SUM(1.0 / ($5 + rnk)) AS score, BOOL_OR(lexical) AS lexical
+			FROM (
+				SELECT id, rnk, false AS lexical FROM vector_rank
+				UNION ALL
+				SELECT id, rnk, true AS lexical FROM lexical_rank
+			) ranks
+			GROUP BY id
+		)
+		SELECT
+			c.id, c.provider, c.section_type, c.title, c.content,
+			c.source_url, c.api_version, c.tags, c.created_at, c.updated_at,
+			COALESCE(e.embedding <-> $2, 2) AS distance,
+			COALESCE(1 - (e.embedding <=> $2), 0) AS similarity,
+			f.lexical
+		FROM fused f
+		JOIN doc_chunk c ON c.id = f.id
+		LEFT JOIN doc_chunk_embedding e ON c.id = e.chunk_id
+		ORDER BY f.score DESC, c.id
+		LIMIT $6
+	`, append([]any{provider, vec, lexical, limit * hybridCandidates, rrfK, limit}, filterArgs(filters)...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanChunks(rows, func(c *DocChunk) []any {
+		return []any{&c.Distance, &c.Similarity, &c.LexicalMatch}
+	})
+}
+
+// scanChunks lê as colunas padrão de doc_chunk; extra devolve destinos para
+// colunas adicionais da query (distance, similarity...), na ordem do SELECT.
+func scanChunks(rows pgx.Rows, extra func(c *DocChunk) []any) ([]DocChunk, error) {
 	defer rows.Close()
 
 	var chunks []DocChunk
 	for rows.Next() {
 		var c DocChunk
-		if err := rows.Scan(
+		dest := []any{
 			&c.ID,
 			&c.Provider,
 			&c.SectionType,
@@ -126,7 +247,11 @@ func (r *PgRepository) SearchSimilarChunks(ctx context.Context, provider Provide
 			&c.Tags,
 			&c.CreatedAt,
 			&c.UpdatedAt,
-		); err != nil {
+		}
+		if extra != nil {
+			dest = append(dest, extra(&c)...)
+		}
+		if err := rows.Scan(dest...); err != nil {
 			return nil, err
 		}
 		chunks = append(chunks, c)
@@ -134,3 +259,38 @@ func (r *PgRepository) SearchSimilarChunks(ctx context.Context, provider Provide
 
 	return chunks, rows.Err()
 }
+
+// lexicalQuery transforma a pergunta em termos separados por "or" para o
+// websearch_to_tsquery. O default (AND de todas as palavras) quase nunca casa
+// com uma pergunta em linguagem natural; o ts_rank_cd já premia quem casa mais termos.
+func lexicalQuery(question string) string {
+	fields := strings.FieldsFunc(question, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-'
+	})
+
+	seen := make(map[string]bool)
+	var terms []string
+	for _, f := range fields {
+		f = strings.Trim(strings.ToLower(f), "-")
+		if f == "" || f == "or" || seen[f] {
+			continue
+		}
+		seen[f] = true
+		terms = append(terms, f)
+	}
+
+	return strings.Join(terms, " or ")
+}
+
+// Store é tudo que a API e o importador usam do armazenamento.
+// Implementado pelo PgRepository e pelo MemoryRepository.
+type Store interface {
+	Repository
+	DocumentRepository
+	ConversationStore
+	ProviderRegistry
+	EndpointCatalog
+	ErrorCodeCatalog
+}
+
+var _ Store = (*PgRepository)(nil)
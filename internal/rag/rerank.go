@@ -0,0 +1,172 @@
+package rag
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Reranker reordena os candidatos da busca antes de irem para o LLM.
+// A implementação devolve os chunks na nova ordem com DocChunk.Score preenchido.
+type Reranker interface {
+	Rerank(ctx context.Context, question string, chunks []DocChunk) ([]DocChunk, error)
+}
+
+// rerankOverfetch: quantas vezes o topK buscamos no repositório quando há reranker,
+// para o reranker ter de onde escolher.
+const rerankOverfetch = 4
+
+// LexicalReranker é um scorer local (sem rede) baseado em features simples:
+// cobertura dos termos da pergunta no conteúdo e no título, tokens exatos
+// (códigos, nomes de campos) e a posição original vinda da busca.
+type LexicalReranker struct{}
+
+func NewLexicalReranker() *LexicalReranker {
+	return &LexicalReranker{}
+}
+
+// pesos das features; somam 1 para o score ficar em [0, 1].
+const (
+	lexWeightCoverage = 0.45
+	lexWeightTitle    = 0.20
+	lexWeightExact    = 0.20
+	lexWeightPrior    = 0.15
+)
+
+func (r *LexicalReranker) Rerank(_ context.Context, question string, chunks []DocChunk) ([]DocChunk, error) {
+	terms := questionTerms(question)
+	exact := exactTokens(question)
+
+	out := make([]DocChunk, len(chunks))
+	copy(out, chunks)
+
+	for i := range out {
+		content := tokenSet(out[i].Content)
+		title := tokenSet(out[i].Title)
+		raw := strings.ToLower(out[i].Title + "\n" + out[i].Content)
+
+		score := lexWeightCoverage*overlap(terms, content) +
+			lexWeightTitle*overlap(terms, title) +
+			lexWeightPrior/float64(i+1)
+
+		if len(exact) > 0 {
+			hits := 0
+			for _, t := range exact {
+				if strings.Contains(raw, t) {
+					hits++
+				}
+			}
+			score += lexWeightExact * float64(hits) / float64(len(exact))
+		} else {
+			// sem tokens "exatos" na pergunta, redistribui o peso para a cobertura
+			score += lexWeightExact * overlap(terms, content)
+		}
+
+		out[i].Score = score
+	}
+
+	sort.SliceStable(out, func(a, b int) bool {
+		return out[a].Score > out[b].Score
+	})
+
+	return out, nil
+}
+
+// -------- helpers --------
+
+var stopwords = map[string]bool{
+	// pt
+	"a": true, "o": true, "as": true, "os": true, "de": true, "da": true, "do": true,
+	"das": true, "dos": true, "e": true, "em": true, "no": true, "na": true, "nos": true,
+	"nas": true, "um": true, "uma": true, "para": true, "por": true, "com": true, "como": true,
+	"que": true, "qual": true, "quais": true, "se": true, "eu": true, "faço": true, "fazer": true,
+	"é": true, "ao": true, "à": true, "mais": true, "sobre": true, "meu": true, "minha": true,
+	// en
+	"the": true, "an": true, "of": true, "to": true, "in": true, "on": true, "for": true,
+	"and": true, "or": true, "is": true, "are": true, "how": true, "what": true, "which": true,
+	"does": true, "i": true, "my": true, "with": true, "it": true, "can": true,
+	"show": true, "me": true, "this": true, "that": true,
+}
+
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+}
+
+func tokenSet(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, t := range tokenize(s) {
+		set[t] = true
+	}
+	return set
+}
+
+// questionTerms devolve os termos relevantes da pergunta (sem stopwords, sem repetição).
+func questionTerms(question string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, t := range tokenize(question) {
+		if stopwords[t] || seen[t] {
+			continue
+		}
+		seen[t] = true
+		terms = append(terms, t)
+	}
+	return terms
+}
+
+// exactTokens pega da pergunta o que parece identificador técnico e deve casar literalmente:
+// trechos entre crases, números (códigos de retorno) e tokens com '_' ou camelCase.
+func exactTokens(question string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	add := func(t string) {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			return
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+
+	parts := strings.Split(question, "`")
+	for i := 1; i < len(parts); i += 2 {
+		add(parts[i])
+	}
+
+	for _, f := range strings.FieldsFunc(question, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		hasDigit, hasUpperInside := false, false
+		for i, r := range f {
+			if unicode.IsDigit(r) {
+				hasDigit = true
+			}
+			if i > 0 && unicode.IsUpper(r) {
+				hasUpperInside = true
+			}
+		}
+		if hasDigit || hasUpperInside || strings.Contains(f, "_") {
+			add(f)
+		}
+	}
+
+	return out
+}
+
+func overlap(terms []string, set map[string]bool) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	hits := 0
+	for _, t := range terms {
+		if set[t] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(terms))
+}
+
+var _ Reranker = (*LexicalReranker)(nil)
@@ -0,0 +1,69 @@
+package rag
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLexicalReranker(t *testing.T) {
+	chunks := []DocChunk{
+		{ID: 1, Title: "Visão geral", Content: "A API usa JSON e autenticação OAuth."},
+		{ID: 2, Title: "Estorno", Content: "O estorno de uma transação é feito via POST /refunds."},
+		{ID: 3, Title: "Códigos de retorno", Content: "Erro returnCode_58: transação não permitida."},
+	}
+
+	out, err := NewLexicalReranker().Rerank(context.Background(), "como fazer o estorno da transação?", chunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out[0].ID != 2 {
+		t.Errorf("order = %d, %d, %d, want Estorno first", out[0].ID, out[1].ID, out[2].ID)
+	}
+	for i, c := range out {
+		if c.Score <= 0 || c.Score > 1 {
+			t.Errorf("out[%d].Score = %v, want (0, 1]", i, c.Score)
+		}
+		if i > 0 && c.Score > out[i-1].Score {
+			t.Errorf("scores not descending: %v > %v", c.Score, out[i-1].Score)
+		}
+	}
+	if chunks[0].ID != 1 || chunks[0].Score != 0 {
+		t.Errorf("input slice was modified: %+v", chunks[0])
+	}
+
+	// token exato (campo/código) vence o chunk que está na frente da busca
+	out, _ = NewLexicalReranker().Rerank(context.Background(), "o que significa returnCode_58?", chunks)
+	if out[0].ID != 3 {
+		t.Errorf("exact token: first = %d, want 3", out[0].ID)
+	}
+
+	// empate: a posição da busca desempata
+	same := []DocChunk{{ID: 1, Content: "captura"}, {ID: 2, Content: "captura"}}
+	out, _ = NewLexicalReranker().Rerank(context.Background(), "captura", same)
+	if out[0].ID != 1 || out[0].Score <= out[1].Score {
+		t.Errorf("tie: %+v", out)
+	}
+}
+
+func TestExactTokens(t *testing.T) {
+	tests := []struct {
+		question string
+		want     []string
+	}{
+		{"o que é o código 58?", []string{"58"}},
+		{"campo `soft_descriptor` e returnCode", []string{"soft_descriptor", "returncode"}},
+		{"como fazer estorno", nil},
+	}
+	for _, tt := range tests {
+		got := exactTokens(tt.question)
+		if len(got) != len(tt.want) {
+			t.Errorf("exactTokens(%q) = %q, want %q", tt.question, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("exactTokens(%q) = %q, want %q", tt.question, got, tt.want)
+			}
+		}
+	}
+}
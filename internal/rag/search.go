@@ -0,0 +1,146 @@
+package rag
+
+import (
+	"context"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// snippetChars é o tamanho aproximado do trecho destacado de cada resultado do /search.
+const snippetChars = 240
+
+// Search roda só a parte de recuperação do Ask (embedding, busca, corte de similaridade
+// e reranker), sem chamar o LLM para gerar resposta.
+func (s *Service) Search(ctx context.Context, req SearchRequest) (*SearchResponse, error) {
+	ask := AskRequest{
+		Question:      req.Query,
+		Provider:      req.Provider,
+		TopK:          req.TopK,
+		Lang:          "auto",
+		SearchMode:    req.SearchMode,
+		MinSimilarity: req.MinSimilarity,
+		Filters:       req.Filters,
+		Providers:     req.Providers,
+	}
+	r, err := s.retrieve(ctx, ask)
+	if err != nil {
+		return nil, err
+	}
+
+	// Gateway indefinido: o /ask pede esclarecimento, o /search busca em todos os candidatos
+	// da votação e devolve a detecção (ambiguous=true) para o cliente decidir.
+	if r.clarification != "" {
+		ambiguous := r.detection
+		ask.Providers = []Provider{ProviderAll}
+		if len(ambiguous.Candidates) > 0 {
+			ask.Providers = make([]Provider, 0, len(ambiguous.Candidates))
+			for _, c := range ambiguous.Candidates {
+				ask.Providers = append(ask.Providers, c.Provider)
+			}
+		}
+		if r, err = s.retrieve(ctx, ask); err != nil {
+			return nil, err
+		}
+		r.detection = ambiguous
+	}
+
+	terms := questionTerms(r.question)
+	results := make([]SearchResult, 0, len(r.chunks))
+	for _, c := range r.chunks {
+		results = append(results, SearchResult{
+			DocChunk: c,
+			Snippet:  highlightSnippet(c.Content, terms, c.Provider),
+		})
+	}
+
+	return &SearchResponse{
+		Query:     r.question,
+		Provider:  r.provider,
+		Providers: r.comparing(),
+		Intent:    &r.intent,
+		Results:   results,
+
+		ProviderDetection: r.detection,
+	}, nil
+}
+
+var wordRe = regexp.MustCompile(`[\p{L}\p{N}_]+`)
+
+// highlightSnippet escolhe a janela do conteúdo com mais termos da busca e envolve
+// cada ocorrência em <mark>. O resto do texto sai escapado (seguro para innerHTML).
+// O nome do provider não é destacado: ele aparece na busca só para escolher o gateway.
+func highlightSnippet(content string, terms []string, provider Provider) string {
+	content = strings.Join(strings.Fields(content), " ")
+
+	want := make(map[string]bool, len(terms))
+	for _, t := range terms {
+		if t != string(provider) {
+			want[t] = true
+		}
+	}
+
+	var hits [][]int
+	for _, loc := range wordRe.FindAllStringIndex(content, -1) {
+		if want[strings.ToLower(content[loc[0]:loc[1]])] {
+			hits = append(hits, loc)
+		}
+	}
+
+	// janela com mais termos distintos; sem nenhum termo, o começo do texto
+	start, best := 0, 0
+	for i, h := range hits {
+		from := max(h[0]-snippetChars/4, 0)
+		distinct := make(map[string]bool)
+		for _, o := range hits[i:] {
+			if o[1] > from+snippetChars {
+				break
+			}
+			distinct[strings.ToLower(content[o[0]:o[1]])] = true
+		}
+		if len(distinct) > best {
+			start, best = from, len(distinct)
+		}
+	}
+	end := min(start+snippetChars, len(content))
+	for start > 0 && !utf8.RuneStart(content[start]) {
+		start--
+	}
+	for end < len(content) && !utf8.RuneStart(content[end]) {
+		end++
+	}
+
+	// ajusta as bordas para fronteiras de palavra
+	if start > 0 {
+		if i := strings.IndexByte(content[start:end], ' '); i >= 0 {
+			start += i + 1
+		}
+	}
+	if end < len(content) {
+		if i := strings.LastIndexByte(content[start:end], ' '); i > 0 {
+			end = start + i
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, h := range hits {
+		if h[0] < start || h[1] > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(content[pos:h[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(content[h[0]:h[1]]))
+		b.WriteString("</mark>")
+		pos = h[1]
+	}
+	b.WriteString(html.EscapeString(content[pos:end]))
+	if end < len(content) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
@@ -0,0 +1,26 @@
+package rag
+
+import (
+	"html"
+	"strings"
+	"testing"
+)
+
+func TestHighlightSnippet(t *testing.T) {
+	content := strings.Repeat("texto de introdução sem nada relevante. ", 20) +
+		"Para o <b>estorno</b> use o endpoint de refunds com o amount. " +
+		strings.Repeat("mais texto no fim. ", 20)
+
+	got := highlightSnippet(content, []string{"estorno", "amount", "rede"}, ProviderRede)
+
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("snippet should be cut on both sides: %q", got)
+	}
+	if !strings.Contains(got, "&lt;b&gt;<mark>estorno</mark>&lt;/b&gt;") || !strings.Contains(got, "<mark>amount</mark>") {
+		t.Errorf("terms not highlighted or html not escaped: %q", got)
+	}
+	plain := html.UnescapeString(strings.NewReplacer("<mark>", "", "</mark>", "").Replace(got))
+	if len(plain) > snippetChars+2*len("…") {
+		t.Errorf("snippet too long (%d bytes)", len(plain))
+	}
+}
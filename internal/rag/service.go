@@ -3,6 +3,8 @@ package rag
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
 	"strings"
 	wl "github.com/abadojack/whatlanggo"
 )
@@ -11,78 +13,605 @@ type Service struct {
 	repo       Repository
 	embeddings EmbeddingsClient
 	llm        LLMClient
+	reranker   Reranker
+
+	// minSimilarity: chunks com similaridade de cosseno abaixo disso não vão para o LLM.
+	minSimilarity float64
+
+	conversations ConversationStore
+
+	// grounding liga a verificação da resposta contra os trechos; groundingRetry
+	// regenera uma vez quando ela encontra itens sem suporte.
+	grounding      bool
+	groundingRetry bool
+
+	// intentMode: como a intenção classificada da pergunta entra na busca (vazio = off).
+	intentMode IntentMode
+
+	// providers valida o provider do request e detecta o gateway pela pergunta.
+	providers ProviderRegistry
+
+	// endpoints é o catálogo extraído no import (nil desliga /providers/{p}/endpoints).
+	endpoints EndpointCatalog
+
+	// errorCodes é a tabela de códigos de erro (nil desliga a consulta direta no Ask).
+	errorCodes ErrorCodeCatalog
+}
+
+// Option configura partes opcionais do Service (reranker etc).
+type Option func(*Service)
+
+// WithReranker liga o estágio de reranking entre a busca e a geração.
+func WithReranker(r Reranker) Option {
+	return func(s *Service) {
+		s.reranker = r
+	}
+}
+
+// WithMinSimilarity define o corte mínimo de similaridade (0 desliga).
+func WithMinSimilarity(v float64) Option {
+	return func(s *Service) {
+		s.minSimilarity = v
+	}
 }
 
-func NewService(repo Repository, embeddings EmbeddingsClient, llm LLMClient) *Service {
-	return &Service{
+// WithConversations liga as conversas multi-turn (conversationId no /ask).
+func WithConversations(store ConversationStore) Option {
+	return func(s *Service) {
+		s.conversations = store
+	}
+}
+
+// WithGroundingCheck liga a verificação de endpoints/URLs/campos/códigos da resposta
+// contra os trechos (warnings no AskResponse). Com retry, uma resposta com avisos é
+// regenerada uma vez e fica a versão com menos avisos.
+func WithGroundingCheck(retry bool) Option {
+	return func(s *Service) {
+		s.grounding = true
+		s.groundingRetry = retry
+	}
+}
+
+// WithIntentRouting usa a intenção da pergunta (3DS, estorno, código de erro...) para
+// subir (boost) ou filtrar (filter) os chunks com section_type/tags correspondentes.
+// Filtros explícitos do request têm precedência sobre o modo filter.
+func WithIntentRouting(mode IntentMode) Option {
+	return func(s *Service) {
+		s.intentMode = mode
+	}
+}
+
+// WithProviders troca o cadastro padrão (rede e entrepay) pelo da tabela provider.
+func WithProviders(reg ProviderRegistry) Option {
+	return func(s *Service) {
+		s.providers = reg
+	}
+}
+
+// WithEndpoints liga o catálogo de endpoints: a listagem por provider e, nas perguntas
+// que procuram um endpoint, os endpoints correspondentes no AskResponse.
+func WithEndpoints(cat EndpointCatalog) Option {
+	return func(s *Service) {
+		s.endpoints = cat
+	}
+}
+
+// WithErrorCodes liga a tabela de códigos de erro: GET /providers/{p}/errors/{code} e,
+// no Ask, a resposta direta da tabela para perguntas sobre um código cadastrado.
+func WithErrorCodes(cat ErrorCodeCatalog) Option {
+	return func(s *Service) {
+		s.errorCodes = cat
+	}
+}
+
+func NewService(repo Repository, embeddings EmbeddingsClient, llm LLMClient, opts ...Option) *Service {
+	s := &Service{
 		repo:       repo,
 		embeddings: embeddings,
 		llm:        llm,
+		providers:  staticProviders(DefaultProviders()),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 func (s *Service) Ask(ctx context.Context, req AskRequest) (*AskResponse, error) {
+	r, err := s.retrieve(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if r.clarification != "" {
+		resp := notFoundResponse("")
+		resp.Answer = r.clarification
+		resp.ConversationID = r.conversationID
+		resp.ProviderDetection = r.detection
+		return resp, nil
+	}
+	if err := s.ensureConversation(ctx, r); err != nil {
+		return nil, err
+	}
+	r.limitToPrompt()
+
+	// código de erro cadastrado: a resposta sai da tabela, sem LLM
+	resp := s.errorCodeAnswer(ctx, r)
+	if resp == nil {
+		resp = notFoundResponse(r.provider)
+		if len(r.chunks) > 0 {
+			// Gera resposta final com LLM usando os chunks
+			resp, err = s.generate(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := s.saveTurn(ctx, r, resp.Answer); err != nil {
+		return nil, err
+	}
+	resp.ConversationID = r.conversationID
+	resp.Intent = &r.intent
+	resp.ProviderDetection = r.detection
+	resp.Endpoints = s.matchEndpoints(ctx, r.provider, r.searchQuery)
+	if cmp := r.comparing(); cmp != nil {
+		resp.Providers = cmp
+		resp.SourcesByProvider = groupSources(resp.Sources)
+	}
+
+	return resp, nil
+}
+
+// AskStream faz a mesma busca do Ask e entrega o resultado como eventos:
+// primeiro as fontes, depois a resposta em pedaços e por fim o "done" com o uso de tokens.
+// Se emit devolver erro (ex: cliente desconectou), a geração é interrompida.
+func (s *Service) AskStream(ctx context.Context, req AskRequest, emit func(StreamEvent) error) error {
+	r, err := s.retrieve(ctx, req)
+	if err != nil {
+		return err
+	}
+	if r.clarification != "" {
+		events := []StreamEvent{
+			{Type: StreamSources, Sources: []SourceRef{}, ConversationID: r.conversationID, ProviderDetection: r.detection},
+			{Type: StreamToken, Token: r.clarification},
+			{Type: StreamDone, Answer: r.clarification, ConversationID: r.conversationID, Usage: &Usage{}},
+		}
+		for _, ev := range events {
+			if err := emit(ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := s.ensureConversation(ctx, r); err != nil {
+		return err
+	}
+	r.limitToPrompt()
+
+	endpoints := s.matchEndpoints(ctx, r.provider, r.searchQuery)
+	var answer strings.Builder
+	usage := &Usage{}
+	done := StreamEvent{Type: StreamDone, ConversationID: r.conversationID}
+
+	if ec := s.errorCodeAnswer(ctx, r); ec != nil {
+		if err := emit(StreamEvent{Type: StreamSources, Provider: r.provider, Sources: ec.Sources, ConversationID: r.conversationID, Intent: &r.intent, ProviderDetection: r.detection, ErrorCode: ec.ErrorCode}); err != nil {
+			return err
+		}
+		if err := emit(StreamEvent{Type: StreamToken, Token: ec.Answer}); err != nil {
+			return err
+		}
+		done.Answer, done.Citations, done.Sources = ec.Answer, ec.Citations, ec.Sources
+	} else if len(r.chunks) == 0 {
+		nf := notFoundResponse(r.provider)
+		if err := emit(StreamEvent{Type: StreamSources, Provider: r.provider, Providers: r.comparing(), Sources: nf.Sources, ConversationID: r.conversationID, Intent: &r.intent, ProviderDetection: r.detection, Endpoints: endpoints}); err != nil {
+			return err
+		}
+		if err := emit(StreamEvent{Type: StreamToken, Token: nf.Answer}); err != nil {
+			return err
+		}
+		answer.WriteString(nf.Answer)
+		done.Answer = nf.Answer
+	} else {
+		sources := buildSources(r.chunks)
+		if err := emit(StreamEvent{Type: StreamSources, Provider: r.provider, Providers: r.comparing(), Sources: sources, ConversationID: r.conversationID, Intent: &r.intent, ProviderDetection: r.detection, Endpoints: endpoints}); err != nil {
+			return err
+		}
+
+		usage, err = s.llm.GenerateAnswerStream(ctx, r.question, r.history, r.chunks, r.provider, r.lang, func(token string) error {
+			answer.WriteString(token)
+			return emit(StreamEvent{Type: StreamToken, Token: token})
+		})
+		if err != nil {
+			return err
+		}
+
+		// os tokens já foram enviados crus; o done traz a versão com as citações validadas
+		done.Answer, done.Citations, done.Sources = applyCitations(answer.String(), sources)
+		if s.grounding {
+			done.Warnings = verifyGrounding(done.Answer, r.question, r.chunks)
+		}
+	}
+
+	if err := s.saveTurn(ctx, r, done.Answer); err != nil {
+		return err
+	}
+
+	done.Usage = usage
+	return emit(done)
+}
+
+// generate chama o LLM e pós-processa a resposta (citações e, se ligada, verificação de grounding).
+func (s *Service) generate(ctx context.Context, r *retrieval) (*AskResponse, error) {
+	build := func(question string) (*AskResponse, error) {
+		answer, err := s.llm.GenerateAnswer(ctx, question, r.history, r.chunks, r.provider, r.lang)
+		if err != nil {
+			return nil, err
+		}
+		resp := &AskResponse{Provider: r.provider}
+		resp.Answer, resp.Citations, resp.Sources = applyCitations(answer, buildSources(r.chunks))
+		if s.grounding {
+			resp.Warnings = verifyGrounding(resp.Answer, r.question, r.chunks)
+		}
+		return resp, nil
+	}
+
+	resp, err := build(r.question)
+	if err != nil || !s.groundingRetry || len(resp.Warnings) == 0 {
+		return resp, err
+	}
+
+	// uma segunda tentativa, avisando o modelo do que ele inventou; falha aqui não derruba o request
+	retry, err := build(r.question + "\n\n" + groundingFeedback(resp.Warnings))
+	if err != nil {
+		log.Printf("grounding retry failed: %v", err)
+		return resp, nil
+	}
+	if len(retry.Warnings) < len(resp.Warnings) {
+		return retry, nil
+	}
+	return resp, nil
+}
+
+// retrieval é o resultado da etapa de busca, comum ao Ask e ao AskStream.
+type retrieval struct {
+	question       string
+	searchQuery    string   // pergunta condensada com o histórico (igual a question sem histórico)
+	provider       Provider // vazio no modo comparação
+	lang           string
+	providers      []Provider // mais de um no modo comparação (provider fica vazio)
+	chunks         []DocChunk // vazio quando nada relevante foi encontrado
+	conversationID string
+	history        []ConversationMessage
+	intent         Intent
+	detection      *ProviderDetection
+
+	// clarification: a pergunta não identificou o gateway; vira a resposta, sem busca nem LLM
+	clarification string
+	// errorCode: a pergunta cita um código cadastrado; a resposta sai da tabela, sem busca nem LLM
+	errorCode *ErrorCode
+}
+
+// limitToPrompt corta os chunks no que o prompt inclui (topK acima de MaxPromptChunks):
+// um trecho que o modelo não viu não vira fonte [n] nem base para o grounding.
+// O /search não passa por aqui e devolve o topK inteiro.
+func (r *retrieval) limitToPrompt() {
+	if len(r.chunks) > MaxPromptChunks {
+		r.chunks = r.chunks[:MaxPromptChunks]
+	}
+}
+
+func (s *Service) retrieve(ctx context.Context, req AskRequest) (*retrieval, error) {
 	q := strings.TrimSpace(req.Question)
 	if q == "" {
 		return nil, errors.New("question is required")
 	}
 
-	// Resolve provider
-	provider := resolveProvider(req.Provider, q)
-	if provider == "" {
-		return nil, errors.New("could not infer provider (ex: use 'rede' ou 'entrepay')")
+	for _, st := range req.Filters.SectionTypes {
+		switch st {
+		case SectionOverview, SectionAuth, SectionEndpoint, Section3DS, SectionErrors:
+		default:
+			return nil, fmt.Errorf("invalid sectionType %q (use overview, auth, endpoint, 3ds ou errors)", st)
+		}
+	}
+
+	// Carrega a conversa, se for um follow-up
+	var conv *Conversation
+	var history []ConversationMessage
+	if req.ConversationID != "" {
+		if s.conversations == nil {
+			return nil, errors.New("conversations are not enabled")
+		}
+		var err error
+		conv, err = s.conversations.GetConversation(ctx, req.ConversationID)
+		if err != nil {
+			return nil, err
+		}
+		if conv == nil {
+			return nil, fmt.Errorf("conversation %q not found", req.ConversationID)
+		}
+		history, err = s.conversations.ListMessages(ctx, conv.ID, historyLimit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Resolve provider(s) (no follow-up sem gateway explícito, vale o da conversa)
+	providers, err := s.Providers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list providers: %w", err)
+	}
+	targets, detection, err := resolveProviders(providers, req, q)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 && conv != nil && conv.Provider != "" {
+		targets = []Provider{conv.Provider}
+		detection = &ProviderDetection{Providers: targets, Method: DetectConversation, Confidence: 1}
+	}
+
+	lang := req.Lang
+	if lang == "" || lang == "auto" {
+		lang = detectLang(q) // nova função logo abaixo
+	}
+
+	// Código de erro cadastrado: a resposta sai da tabela, sem condensar, buscar nem rerankear
+	fromTable := func(provider Provider) *retrieval {
+		ec := s.questionErrorCode(ctx, provider, q)
+		if ec == nil {
+			return nil
+		}
+		r := &retrieval{
+			question:    q,
+			searchQuery: q,
+			provider:    provider,
+			providers:   []Provider{provider},
+			lang:        lang,
+			history:     history,
+			intent:      classifyIntent(q),
+			detection:   detection,
+			errorCode:   ec,
+		}
+		if conv != nil {
+			r.conversationID = conv.ID
+		}
+		return r
+	}
+	if len(targets) == 1 {
+		if r := fromTable(targets[0]); r != nil {
+			return r, nil
+		}
+	}
+
+	// Follow-up: condensa histórico + pergunta numa consulta autossuficiente para a busca
+	searchQ := q
+	if len(history) > 0 {
+		condensed, err := s.llm.CondenseQuestion(ctx, history, q)
+		if err != nil {
+			return nil, fmt.Errorf("condense question: %w", err)
+		}
+		searchQ = condensed
 	}
 
 	// Embedding da pergunta
-	vec, err := s.embeddings.Embed(ctx, q)
+	vec, err := s.embeddings.Embed(ctx, searchQ)
 	if err != nil {
 		return nil, err
 	}
 
+	// Sem gateway inequívoco: votação pelos trechos mais próximos de todos os providers
+	if len(targets) == 0 {
+		detection, err = s.voteProvider(ctx, providers, vec, detection.weak)
+		if err != nil {
+			return nil, err
+		}
+		if detection == nil {
+			return nil, fmt.Errorf("could not infer provider (ex: use %s, ou \"providers\": [\"all\"] para comparar)", providerHint(providers))
+		}
+		if detection.Ambiguous {
+			r := &retrieval{
+				question:      q,
+				searchQuery:   searchQ,
+				lang:          lang,
+				history:       history,
+				detection:     detection,
+				clarification: clarificationAnswer(detection, providers, lang),
+			}
+			if conv != nil {
+				r.conversationID = conv.ID
+			}
+			return r, nil
+		}
+		targets = detection.Providers
+		if len(targets) == 1 {
+			if r := fromTable(targets[0]); r != nil {
+				return r, nil
+			}
+		}
+	}
+
 	topK := req.TopK
 	if topK <= 0 {
 		topK = 5
 	}
+	// na comparação o topK vale por provider, sem passar do total que cabe no prompt
+	if len(targets) > 1 {
+		topK = max(1, min(topK, comparisonMaxChunks/len(targets)))
+	}
 
-	if req.Lang == "" || req.Lang == "auto" {
-      req.Lang = detectLang(q) // nova função logo abaixo
-  }
+	intent := classifyIntent(searchQ)
+	routing := s.intentMode
+	if intent.empty() {
+		routing = IntentOff
+	}
 
-	// Busca vetorial
-	chunks, err := s.repo.SearchSimilarChunks(ctx, provider, vec, topK)
-	if err != nil {
-		return nil, err
+	// Com reranker buscamos mais candidatos e deixamos ele escolher o topK;
+	// o boost por intenção também precisa de candidatos além do topK para reordenar
+	fetchK := topK
+	if s.reranker != nil {
+		fetchK = topK * rerankOverfetch
+	} else if routing == IntentBoost {
+		fetchK = topK * 2
+	}
+
+	minSim := s.minSimilarity
+	if req.MinSimilarity != nil {
+		minSim = *req.MinSimilarity
+	}
+
+	// Busca (vetorial ou híbrida)
+	search := func(provider Provider, filters SearchFilters) ([]DocChunk, error) {
+		var chunks []DocChunk
+		var err error
+		switch req.SearchMode {
+		case "", SearchVector:
+			chunks, err = s.repo.SearchSimilarChunks(ctx, provider, vec, fetchK, filters)
+		case SearchHybrid:
+			chunks, err = s.repo.SearchHybridChunks(ctx, provider, searchQ, vec, fetchK, filters)
+		default:
+			return nil, fmt.Errorf("invalid searchMode %q (use 'vector' ou 'hybrid')", req.SearchMode)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return filterRelevant(chunks, minSim), nil
 	}
-	if len(chunks) == 0 {
-		return &AskResponse{
-			Answer:   "Não encontrei nada na documentação indexada para essa pergunta.",
-			Provider: provider,
-			Sources:  []SourceRef{},
-		}, nil
+
+	// Busca + intenção + reranker + topK de um provider
+	searchProvider := func(provider Provider) ([]DocChunk, IntentMode, error) {
+		var chunks []DocChunk
+		var applied IntentMode
+		if routing == IntentFilter && req.Filters.empty() {
+			// filtro pela intenção; sem resultado, vale a busca normal
+			chunks, err = search(provider, intent.filters())
+			if err != nil {
+				return nil, "", err
+			}
+			if len(chunks) > 0 {
+				applied = IntentFilter
+			}
+		}
+		if applied == "" {
+			chunks, err = search(provider, req.Filters)
+			if err != nil {
+				return nil, "", err
+			}
+			if routing == IntentBoost && len(chunks) > 0 {
+				boostByIntent(chunks, intent)
+				applied = IntentBoost
+			}
+		}
+
+		// reranker é opcional: se falhar, fica a ordem da busca
+		if s.reranker != nil && len(chunks) > 0 {
+			if reranked, err := s.reranker.Rerank(ctx, searchQ, chunks); err != nil {
+				log.Printf("rerank failed, keeping search order: %v", err)
+			} else {
+				chunks = reranked
+			}
+		}
+		if len(chunks) > topK {
+			chunks = chunks[:topK]
+		}
+		return chunks, applied, nil
+	}
+
+	// na comparação os chunks ficam agrupados por provider, na ordem de targets
+	var chunks []DocChunk
+	for _, p := range targets {
+		found, applied, err := searchProvider(p)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, found...)
+		if applied != "" {
+			intent.Applied = applied
+		}
 	}
 
-	// Gera resposta final com LLM usando os chunks
-	answer, err := s.llm.GenerateAnswer(ctx, q, chunks, provider, req.Lang)
+	r := &retrieval{
+		question:    q,
+		searchQuery: searchQ,
+		providers:   targets,
+		lang:        lang,
+		chunks:      chunks,
+		history:     history,
+		intent:      intent,
+		detection:   detection,
+	}
+	if len(targets) == 1 {
+		r.provider = targets[0]
+	}
+	if conv != nil {
+		r.conversationID = conv.ID
+	}
+	return r, nil
+}
+
+// ensureConversation cria a conversa (quando o store está ligado e o request não trouxe uma),
+// para o cliente receber o conversationId já na primeira pergunta.
+func (s *Service) ensureConversation(ctx context.Context, r *retrieval) error {
+	if s.conversations == nil || r.conversationID != "" {
+		return nil
+	}
+	id, err := s.conversations.CreateConversation(ctx, r.provider)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("create conversation: %w", err)
+	}
+	r.conversationID = id
+	return nil
+}
+
+// saveTurn grava a pergunta e a resposta no histórico da conversa.
+func (s *Service) saveTurn(ctx context.Context, r *retrieval, answer string) error {
+	if s.conversations == nil || r.conversationID == "" {
+		return nil
 	}
+	return s.conversations.AppendMessages(ctx, r.conversationID,
+		ConversationMessage{Role: RoleUser, Content: r.question},
+		ConversationMessage{Role: RoleAssistant, Content: answer},
+	)
+}
 
-	// Monta fontes
+func buildSources(chunks []DocChunk) []SourceRef {
 	sources := make([]SourceRef, 0, len(chunks))
-	for _, c := range chunks {
+	for i, c := range chunks {
 		sources = append(sources, SourceRef{
-			ChunkID:   c.ID,
-			Title:     c.Title,
-			Provider:  c.Provider,
-			SourceURL: c.SourceURL,
+			Ref:        i + 1,
+			ChunkID:    c.ID,
+			Title:      c.Title,
+			Provider:   c.Provider,
+			SourceURL:  c.SourceURL,
+			Distance:   c.Distance,
+			Similarity: c.Similarity,
+			Score:      c.Score,
 		})
 	}
+	return sources
+}
 
+func notFoundResponse(provider Provider) *AskResponse {
 	return &AskResponse{
-		Answer:   answer,
-		Provider: provider,
-		Sources:  sources,
-	}, nil
+		Answer:    "Não encontrei nada na documentação indexada para essa pergunta.",
+		Provider:  provider,
+		Sources:   []SourceRef{},
+		Citations: []Citation{},
+	}
+}
+
+// filterRelevant remove chunks abaixo do corte de similaridade.
+// Chunks que casaram no full-text (modo híbrido) passam mesmo assim:
+// é justamente o caso de token exato que o embedding não pega.
+func filterRelevant(chunks []DocChunk, minSimilarity float64) []DocChunk {
+	if minSimilarity <= 0 {
+		return chunks
+	}
+	out := chunks[:0]
+	for _, c := range chunks {
+		if c.Similarity >= minSimilarity || c.LexicalMatch {
+			out = append(out, c)
+		}
+	}
+	return out
 }
 
 func detectLang(s string) string {
@@ -96,22 +625,40 @@ func detectLang(s string) string {
     }
 }
 
-func resolveProvider(p *Provider, question string) Provider {
-	if p != nil && *p != "" {
-		return *p
+// Providers devolve os gateways habilitados (GET /providers).
+func (s *Service) Providers(ctx context.Context) ([]ProviderInfo, error) {
+	all, err := s.providers.ListProviders(ctx)
+	if err != nil {
+		return nil, err
 	}
+	return enabledProviders(all), nil
+}
 
-	q := strings.ToLower(question)
+// ErrUnknownProvider indica um slug/apelido fora do cadastro de providers habilitados.
+var ErrUnknownProvider = errors.New("unknown provider")
+
+// providerInfo acha um provider habilitado pelo slug ou apelido (rotas /providers/{p}/...).
+func (s *Service) providerInfo(ctx context.Context, p Provider) (*ProviderInfo, error) {
+	providers, err := s.Providers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list providers: %w", err)
+	}
+	info := LookupProvider(providers, string(p))
+	if info == nil {
+		return nil, fmt.Errorf("%w %q (use %s)", ErrUnknownProvider, p, providerHint(providers))
+	}
+	return info, nil
+}
 
-	switch {
-	case strings.Contains(q, "entrepay"):
-		return ProviderEntrepay
-	case strings.Contains(q, "e-rede"),
-		strings.Contains(q, "erede"),
-		strings.Contains(q, "e rede"),
-		strings.Contains(q, "rede"):
-		return ProviderRede
-	default:
-		return ""
+// resolveProvider valida o provider do request (slug ou apelido) contra o cadastro;
+// sem provider, detecta pelos apelidos na pergunta ("" se nenhum aparece).
+func resolveProvider(providers []ProviderInfo, p *Provider, question string) (Provider, error) {
+	if p != nil && *p != "" {
+		info := LookupProvider(providers, string(*p))
+		if info == nil {
+			return "", fmt.Errorf("%w %q (use %s)", ErrUnknownProvider, *p, providerHint(providers))
+		}
+		return info.Slug, nil
 	}
+	return detectProvider(providers, question), nil
 }
@@ -0,0 +1,446 @@
+package rag_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/josinaldojr/payment-gateway-rag/internal/llm/fake"
+	"github.com/josinaldojr/payment-gateway-rag/internal/rag"
+)
+
+func newTestService(t *testing.T, opts ...rag.Option) (*rag.Service, *rag.MemoryRepository) {
+	t.Helper()
+
+	emb, err := fake.New(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := rag.NewMemoryRepository("", rag.MetricCosine)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunks := []rag.DocChunk{
+		{Provider: rag.ProviderRede, Title: "Autenticação", Content: "OAuth 2.0 com client_id e client_secret gera o access_token."},
+		{Provider: rag.ProviderRede, Title: "Estorno", Content: "Estorno via POST /v1/transactions/{tid}/refunds informando o amount."},
+		{Provider: rag.ProviderRede, Title: "Códigos de retorno", Content: "Código 58: transação não permitida para o cartão."},
+		{Provider: rag.ProviderEntrepay, Title: "Estorno", Content: "Cancelamento Entrepay via DELETE /charges/{id}."},
+	}
+	for i := range chunks {
+		vec, err := emb.Embed(context.Background(), chunks[i].Title+" "+chunks[i].Content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.InsertChunk(context.Background(), &chunks[i], vec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts = append([]rag.Option{rag.WithMinSimilarity(0)}, opts...)
+	return rag.NewService(repo, emb, emb, opts...), repo
+}
+
+func TestAskFiltersByProvider(t *testing.T) {
+	svc, _ := newTestService(t)
+
+	resp, err := svc.Ask(context.Background(), rag.AskRequest{Question: "como fazer estorno na entrepay?", Lang: "pt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Provider != rag.ProviderEntrepay {
+		t.Fatalf("provider = %q", resp.Provider)
+	}
+	for _, s := range resp.Sources {
+		if s.Provider != rag.ProviderEntrepay {
+			t.Errorf("source from another provider: %+v", s)
+		}
+	}
+}
+
+func TestAskRanksClosestChunkFirst(t *testing.T) {
+	svc, _ := newTestService(t)
+
+	resp, err := svc.Ask(context.Background(), rag.AskRequest{Question: "rede estorno refunds amount", TopK: 2, Lang: "pt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Sources) != 2 || resp.Sources[0].Title != "Estorno" {
+		t.Fatalf("sources = %+v, want Estorno first", resp.Sources)
+	}
+	if resp.Sources[0].Similarity <= resp.Sources[1].Similarity {
+		t.Errorf("similarity not descending: %+v", resp.Sources)
+	}
+}
+
+func TestAskMinSimilarityReturnsNotFound(t *testing.T) {
+	svc, _ := newTestService(t)
+
+	minSim := 0.99
+	resp, err := svc.Ask(context.Background(), rag.AskRequest{Question: "rede webhook de chargeback", MinSimilarity: &minSim, Lang: "pt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Sources) != 0 {
+		t.Fatalf("sources = %+v, want none", resp.Sources)
+	}
+}
+
+func TestAskHybridKeepsLexicalMatch(t *testing.T) {
+	svc, _ := newTestService(t)
+
+	minSim := 0.99
+	resp, err := svc.Ask(context.Background(), rag.AskRequest{
+		Question:      "rede código 58",
+		SearchMode:    rag.SearchHybrid,
+		MinSimilarity: &minSim,
+		Lang:          "pt",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Sources) == 0 || resp.Sources[0].Title != "Códigos de retorno" {
+		t.Fatalf("sources = %+v, want Códigos de retorno", resp.Sources)
+	}
+}
+
+func TestAskFollowUpUsesConversation(t *testing.T) {
+	svc, repo := newTestService(t)
+	svc = rag.NewService(repo, mustFake(t), mustFake(t), rag.WithMinSimilarity(0), rag.WithConversations(repo))
+
+	first, err := svc.Ask(context.Background(), rag.AskRequest{Question: "como funciona o estorno na rede?", Lang: "pt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ConversationID == "" {
+		t.Fatal("conversationId not returned")
+	}
+
+	// follow-up sem gateway: vale o provider da conversa
+	second, err := svc.Ask(context.Background(), rag.AskRequest{Question: "e qual o endpoint?", ConversationID: first.ConversationID, Lang: "pt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Provider != rag.ProviderRede || second.ConversationID != first.ConversationID {
+		t.Fatalf("got provider=%q conversation=%q", second.Provider, second.ConversationID)
+	}
+
+	msgs, err := repo.ListMessages(context.Background(), first.ConversationID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 4 || msgs[0].Role != rag.RoleUser || msgs[3].Role != rag.RoleAssistant {
+		t.Fatalf("messages = %+v", msgs)
+	}
+}
+
+func TestAskUnknownConversation(t *testing.T) {
+	svc, repo := newTestService(t)
+	svc = rag.NewService(repo, mustFake(t), mustFake(t), rag.WithConversations(repo))
+
+	_, err := svc.Ask(context.Background(), rag.AskRequest{Question: "estorno rede", ConversationID: "nao-existe"})
+	if err == nil {
+		t.Fatal("expected error for unknown conversation")
+	}
+}
+
+func mustFake(t *testing.T) *fake.Client {
+	t.Helper()
+	c, err := fake.New(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// hallucinatingLLM inventa um endpoint na primeira resposta e corrige quando recebe o aviso.
+type hallucinatingLLM struct {
+	*fake.Client
+	calls int
+}
+
+func (h *hallucinatingLLM) GenerateAnswer(_ context.Context, question string, _ []rag.ConversationMessage, _ []rag.DocChunk, _ rag.Provider, _ string) (string, error) {
+	h.calls++
+	if strings.Contains(question, "/v9/refunds") {
+		return "Use POST /v1/transactions/{tid}/refunds [1].", nil
+	}
+	return "Use POST /v9/refunds [1].", nil
+}
+
+func TestAskGroundingRetry(t *testing.T) {
+	_, repo := newTestService(t)
+
+	for _, retry := range []bool{false, true} {
+		llm := &hallucinatingLLM{Client: mustFake(t)}
+		svc := rag.NewService(repo, mustFake(t), llm, rag.WithMinSimilarity(0), rag.WithGroundingCheck(retry))
+
+		resp, err := svc.Ask(context.Background(), rag.AskRequest{Question: "estorno na rede", TopK: 4, Lang: "pt"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !retry {
+			if len(resp.Warnings) != 1 || resp.Warnings[0].Value != "/v9/refunds" || llm.calls != 1 {
+				t.Errorf("without retry: warnings = %+v, calls = %d", resp.Warnings, llm.calls)
+			}
+			continue
+		}
+		if len(resp.Warnings) != 0 || llm.calls != 2 || !strings.Contains(resp.Answer, "/v1/transactions") {
+			t.Errorf("with retry: answer = %q, warnings = %+v, calls = %d", resp.Answer, resp.Warnings, llm.calls)
+		}
+	}
+}
+
+func TestAskIntentRouting(t *testing.T) {
+	_, repo := newTestService(t)
+	emb := mustFake(t)
+
+	table := rag.DocChunk{Provider: rag.ProviderRede, SectionType: rag.SectionErrors, Title: "Tabela de retornos", Content: "58 não permitida; 05 não autorizada."}
+	vec, err := emb.Embed(context.Background(), table.Title+" "+table.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.InsertChunk(context.Background(), &table, vec); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := rag.NewService(repo, emb, emb, rag.WithMinSimilarity(0), rag.WithIntentRouting(rag.IntentFilter))
+	resp, err := svc.Ask(context.Background(), rag.AskRequest{Question: "rede código 58", Lang: "pt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Intent == nil || resp.Intent.Name != rag.IntentErrorCode || resp.Intent.Applied != rag.IntentFilter {
+		t.Fatalf("intent = %+v", resp.Intent)
+	}
+	if len(resp.Sources) != 1 || resp.Sources[0].ChunkID != table.ID {
+		t.Fatalf("sources = %+v, want only the errors chunk", resp.Sources)
+	}
+
+	// pergunta sem chunk da intenção: o filtro não acha nada e a busca normal vale
+	resp, err = svc.Ask(context.Background(), rag.AskRequest{Question: "rede 3DS", Lang: "pt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Intent.Applied != "" || len(resp.Sources) == 0 {
+		t.Fatalf("fallback: intent = %+v, sources = %d", resp.Intent, len(resp.Sources))
+	}
+}
+
+func TestAskCompareProviders(t *testing.T) {
+	svc, _ := newTestService(t)
+
+	// pergunta citando os dois gateways entra sozinha no modo comparação
+	resp, err := svc.Ask(context.Background(), rag.AskRequest{Question: "como o estorno difere entre rede e entrepay?", Lang: "pt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Provider != "" || len(resp.Providers) != 2 || resp.Providers[0] != rag.ProviderRede {
+		t.Fatalf("provider = %q, providers = %v", resp.Provider, resp.Providers)
+	}
+	if len(resp.SourcesByProvider[rag.ProviderRede]) == 0 || len(resp.SourcesByProvider[rag.ProviderEntrepay]) == 0 {
+		t.Fatalf("sourcesByProvider = %+v", resp.SourcesByProvider)
+	}
+	for p, sources := range resp.SourcesByProvider {
+		for _, s := range sources {
+			if s.Provider != p {
+				t.Errorf("source %+v grouped under %q", s, p)
+			}
+		}
+	}
+
+	// "all" sem gateway na pergunta
+	resp, err = svc.Ask(context.Background(), rag.AskRequest{Question: "como funciona o estorno?", Providers: []rag.Provider{rag.ProviderAll}, TopK: 1, Lang: "pt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Providers) != 2 || len(resp.Sources) != 2 {
+		t.Fatalf("all: providers = %v, sources = %+v", resp.Providers, resp.Sources)
+	}
+
+	if _, err := svc.Ask(context.Background(), rag.AskRequest{Question: "estorno", Providers: []rag.Provider{"rede", "stone"}}); err == nil {
+		t.Fatal("expected error for unknown provider in providers")
+	}
+}
+
+func TestAskDetectsProviderByVector(t *testing.T) {
+	svc, _ := newTestService(t)
+
+	// sem gateway na pergunta: só a rede fala de OAuth
+	resp, err := svc.Ask(context.Background(), rag.AskRequest{Question: "como gerar o access_token com client_secret?", Lang: "pt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := resp.ProviderDetection; resp.Provider != rag.ProviderRede || d == nil || d.Method != rag.DetectVector {
+		t.Fatalf("provider = %q, detection = %+v", resp.Provider, resp.ProviderDetection)
+	}
+
+	// "rede" é apelido ambíguo: entra na votação em vez de decidir sozinho
+	resp, err = svc.Ask(context.Background(), rag.AskRequest{Question: "erro de rede no DELETE /charges/{id}", Lang: "pt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := resp.ProviderDetection; d == nil || d.Method != rag.DetectVector || len(d.Candidates) != 2 {
+		t.Fatalf("provider = %q, detection = %+v", resp.Provider, resp.ProviderDetection)
+	}
+
+	// explícito continua valendo, sem votação
+	entrepay := rag.ProviderEntrepay
+	resp, err = svc.Ask(context.Background(), rag.AskRequest{Question: "estorno", Provider: &entrepay, Lang: "pt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := resp.ProviderDetection; d == nil || d.Method != rag.DetectExplicit || d.Confidence != 1 {
+		t.Fatalf("detection = %+v", resp.ProviderDetection)
+	}
+}
+
+// countingLLM conta as chamadas ao modelo (a consulta de código não deve chamar o modelo,
+// nem para condensar o follow-up).
+type countingLLM struct {
+	*fake.Client
+	calls int
+}
+
+func (c *countingLLM) GenerateAnswer(ctx context.Context, question string, history []rag.ConversationMessage, chunks []rag.DocChunk, provider rag.Provider, lang string) (string, error) {
+	c.calls++
+	return c.Client.GenerateAnswer(ctx, question, history, chunks, provider, lang)
+}
+
+func (c *countingLLM) CondenseQuestion(ctx context.Context, history []rag.ConversationMessage, question string) (string, error) {
+	c.calls++
+	return c.Client.CondenseQuestion(ctx, history, question)
+}
+
+// countingReranker é o lugar do reranker por LLM: também não pode rodar na consulta de código.
+type countingReranker struct{ calls int }
+
+func (r *countingReranker) Rerank(_ context.Context, _ string, chunks []rag.DocChunk) ([]rag.DocChunk, error) {
+	r.calls++
+	return chunks, nil
+}
+
+// topK acima do que cabe no prompt: fontes e grounding ficam nos trechos que o modelo viu
+func TestAskLimitsChunksToPrompt(t *testing.T) {
+	svc, repo := newTestService(t)
+	emb := mustFake(t)
+	for i := range 12 {
+		c := rag.DocChunk{Provider: rag.ProviderRede, Title: fmt.Sprintf("Estorno %d", i), Content: "estorno via POST /v1/transactions/{tid}/refunds"}
+		vec, err := emb.Embed(context.Background(), c.Title+" "+c.Content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.InsertChunk(context.Background(), &c, vec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := rag.AskRequest{Question: "estorno na rede", TopK: 15, Lang: "pt"}
+
+	resp, err := svc.Ask(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range resp.Sources {
+		if src.Ref > rag.MaxPromptChunks {
+			t.Errorf("source [%d] was never sent to the model", src.Ref)
+		}
+	}
+
+	var streamed []rag.SourceRef
+	err = svc.AskStream(context.Background(), req, func(ev rag.StreamEvent) error {
+		if ev.Type == rag.StreamSources {
+			streamed = ev.Sources
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streamed) != rag.MaxPromptChunks {
+		t.Errorf("stream advertised %d sources, want %d", len(streamed), rag.MaxPromptChunks)
+	}
+
+	// o /search continua devolvendo o topK inteiro
+	search, err := svc.Search(context.Background(), rag.SearchRequest{Query: "estorno na rede", TopK: 15})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(search.Results) != 15 {
+		t.Errorf("search results = %d, want 15", len(search.Results))
+	}
+}
+
+type failingReranker struct{}
+
+func (failingReranker) Rerank(context.Context, string, []rag.DocChunk) ([]rag.DocChunk, error) {
+	return nil, errors.New("invalid rerank json")
+}
+
+// reranker é opcional: falha nele não pode custar a resposta
+func TestAskRerankFailureKeepsSearchOrder(t *testing.T) {
+	req := rag.AskRequest{Question: "rede estorno refunds amount", TopK: 2, Lang: "pt"}
+
+	svc, _ := newTestService(t)
+	want, err := svc.Ask(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc, _ = newTestService(t, rag.WithReranker(failingReranker{}))
+	got, err := svc.Ask(context.Background(), req)
+	if err != nil {
+		t.Fatalf("rerank error failed the answer: %v", err)
+	}
+	if len(got.Sources) != len(want.Sources) || got.Sources[0].ChunkID != want.Sources[0].ChunkID {
+		t.Errorf("sources = %+v, want search order %+v", got.Sources, want.Sources)
+	}
+}
+
+func TestAskErrorCodeLookup(t *testing.T) {
+	_, repo := newTestService(t)
+	emb := mustFake(t)
+
+	table := rag.DocChunk{Provider: rag.ProviderRede, SectionType: rag.SectionErrors, Title: "Códigos de retorno", Content: "| código | mensagem |\n| --- | --- |\n| 58 | Transação não permitida para o cartão |\n| AC01 | Cartão vencido |"}
+	table.ErrorCodes = rag.ExtractErrorCodes(table.Content)
+	vec, err := emb.Embed(context.Background(), table.Title+" "+table.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.InsertChunk(context.Background(), &table, vec); err != nil {
+		t.Fatal(err)
+	}
+
+	llm := &countingLLM{Client: emb}
+	reranker := &countingReranker{}
+	svc := rag.NewService(repo, emb, llm, rag.WithMinSimilarity(0), rag.WithErrorCodes(repo), rag.WithConversations(repo), rag.WithReranker(reranker))
+
+	resp, err := svc.Ask(context.Background(), rag.AskRequest{Question: "o que significa o código 58 na rede?", Lang: "pt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if llm.calls != 0 || reranker.calls != 0 || resp.ErrorCode == nil || resp.ErrorCode.ChunkID != table.ID {
+		t.Fatalf("calls = %d/%d, errorCode = %+v", llm.calls, reranker.calls, resp.ErrorCode)
+	}
+	if len(resp.Sources) != 1 || resp.Sources[0].ChunkID != table.ID || !strings.Contains(resp.Answer, "não permitida") {
+		t.Fatalf("answer = %q, sources = %+v", resp.Answer, resp.Sources)
+	}
+
+	// follow-up com código alfanumérico: nem condensa a pergunta com o histórico
+	resp, err = svc.Ask(context.Background(), rag.AskRequest{Question: "e o erro AC01?", ConversationID: resp.ConversationID, Lang: "pt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if llm.calls != 0 || reranker.calls != 0 || resp.ErrorCode == nil || resp.ErrorCode.Code != "AC01" {
+		t.Fatalf("calls = %d/%d, errorCode = %+v", llm.calls, reranker.calls, resp.ErrorCode)
+	}
+
+	// código fora da tabela segue para o LLM
+	resp, err = svc.Ask(context.Background(), rag.AskRequest{Question: "o que significa o código 99 na rede?", Lang: "pt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if llm.calls != 1 || reranker.calls != 1 || resp.ErrorCode != nil {
+		t.Fatalf("calls = %d, errorCode = %+v", llm.calls, resp.ErrorCode)
+	}
+}
@@ -0,0 +1,15 @@
+-- Busca lexical (full-text) usada no modo híbrido junto com o pgvector.
+-- Indexa título (peso A) e conteúdo (peso B) nas configs portuguese e english,
+-- já que as docs misturam os dois idiomas (nomes de campos, códigos etc).
+ALTER TABLE doc_chunk
+    ADD COLUMN IF NOT EXISTS search_tsv TSVECTOR
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('portuguese', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('portuguese', content), 'B') ||
+        setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('english', content), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_doc_chunk_search_tsv
+    ON doc_chunk
+    USING GIN (search_tsv);
@@ -0,0 +1,19 @@
+-- Documento de origem (arquivo ou URL) de onde os chunks foram gerados.
+-- O content_hash permite que o import-doc pule documentos inalterados
+-- e substitua os chunks dos alterados numa transação só.
+CREATE TABLE IF NOT EXISTS document (
+    id           BIGSERIAL PRIMARY KEY,
+    provider     TEXT NOT NULL,
+    source       TEXT NOT NULL,        -- caminho do arquivo ou URL
+    content_hash TEXT NOT NULL,
+    api_version  TEXT,
+    imported_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, source)
+);
+
+-- Chunks antigos (importados antes dessa migration) ficam com document_id NULL.
+ALTER TABLE doc_chunk
+    ADD COLUMN IF NOT EXISTS document_id BIGINT REFERENCES document(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_doc_chunk_document
+    ON doc_chunk (document_id);
@@ -0,0 +1,19 @@
+-- Conversas multi-turn do /ask (conversationId).
+-- gen_random_uuid() é nativo a partir do Postgres 13.
+CREATE TABLE IF NOT EXISTS conversation (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    provider   TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS conversation_message (
+    id              BIGSERIAL PRIMARY KEY,
+    conversation_id UUID NOT NULL REFERENCES conversation(id) ON DELETE CASCADE,
+    role            TEXT NOT NULL CHECK (role IN ('user', 'assistant')),
+    content         TEXT NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_message_conversation
+    ON conversation_message (conversation_id, id);
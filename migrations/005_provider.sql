@@ -0,0 +1,18 @@
+-- Cadastro de gateways: adicionar Cielo, Stone etc. é inserir uma linha aqui.
+-- aliases são os nomes que identificam o provider numa pergunta (palavra inteira, sem caixa).
+CREATE TABLE IF NOT EXISTS provider (
+    slug                TEXT PRIMARY KEY,
+    display_name        TEXT NOT NULL,
+    aliases             TEXT[] NOT NULL DEFAULT '{}',
+    doc_urls            TEXT[] NOT NULL DEFAULT '{}',
+    default_api_version TEXT,
+    enabled             BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO provider (slug, display_name, aliases, doc_urls)
+VALUES
+    ('rede', 'e-Rede', ARRAY['rede', 'e-rede', 'erede', 'e rede', 'userede'], ARRAY['https://developer.userede.com.br/e-rede']),
+    ('entrepay', 'Entrepay', ARRAY['entrepay'], '{}')
+ON CONFLICT (slug) DO NOTHING;
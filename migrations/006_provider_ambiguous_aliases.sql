@@ -0,0 +1,6 @@
+-- Apelidos que também são palavras comuns ("rede" = network; "e rede" em "internet e rede"):
+-- sozinhos na pergunta não decidem o provider, só desempatam a detecção por busca vetorial.
+ALTER TABLE provider
+    ADD COLUMN IF NOT EXISTS ambiguous_aliases TEXT[] NOT NULL DEFAULT '{}';
+
+UPDATE provider SET ambiguous_aliases = ARRAY['rede', 'e rede'] WHERE slug = 'rede';
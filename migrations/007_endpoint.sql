@@ -0,0 +1,19 @@
+-- Catálogo de endpoints extraído dos chunks pelo import-doc (método + path, descrição e
+-- parâmetros). Cada linha aponta para o chunk de onde saiu: reimportar o documento troca
+-- os chunks e os endpoints vão junto (ON DELETE CASCADE).
+CREATE TABLE IF NOT EXISTS endpoint (
+    id          BIGSERIAL PRIMARY KEY,
+    provider    TEXT NOT NULL,
+    method      TEXT NOT NULL,
+    path        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    parameters  JSONB NOT NULL DEFAULT '[]', -- [{"name", "in", "type", "required", "description"}]
+    chunk_id    BIGINT NOT NULL REFERENCES doc_chunk(id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_endpoint_provider_path
+    ON endpoint (provider, path);
+
+CREATE INDEX IF NOT EXISTS idx_endpoint_chunk
+    ON endpoint (chunk_id);
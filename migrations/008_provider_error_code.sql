@@ -0,0 +1,20 @@
+-- Códigos de erro/retorno lidos pelo import-doc das tabelas dos chunks de section_type
+-- errors. category e retryable vêm das colunas da tabela da doc ou são estimados pela
+-- mensagem (retryable NULL = sem indicação). Reimportar o documento troca os códigos
+-- junto com os chunks (ON DELETE CASCADE).
+CREATE TABLE IF NOT EXISTS provider_error_code (
+    id         BIGSERIAL PRIMARY KEY,
+    provider   TEXT NOT NULL,
+    code       TEXT NOT NULL,
+    message    TEXT NOT NULL,
+    category   TEXT NOT NULL DEFAULT '',
+    retryable  BOOLEAN,
+    chunk_id   BIGINT NOT NULL REFERENCES doc_chunk(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_provider_error_code_lookup
+    ON provider_error_code (provider, code);
+
+CREATE INDEX IF NOT EXISTS idx_provider_error_code_chunk
+    ON provider_error_code (chunk_id);